@@ -12,6 +12,7 @@ import (
 	"github.com/it-novum/openitcockpit-agent-go/config"
 	"github.com/it-novum/openitcockpit-agent-go/loghandler"
 	"github.com/it-novum/openitcockpit-agent-go/pushclient"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
 	"github.com/it-novum/openitcockpit-agent-go/webserver"
 	log "github.com/sirupsen/logrus"
 )
@@ -34,26 +35,47 @@ type AgentInstance struct {
 	checkResult                  chan map[string]interface{}
 	customCheckResultChan        chan *checkrunner.CustomCheckResult
 	prometheusExporterResultChan chan *checkrunner.PrometheusExporterResult
+	probeResultChan              chan *checkrunner.ProbeResultMessage
 
-	customCheckResults map[string]interface{}
+	customCheckResults       map[string]*utils.CommandResult
+	customCheckStateFile     string
+	customCheckStateDirty    bool
+	customCheckConfiguration *config.Configuration
 
 	prometheusExporterResults map[string]string
 
+	probeResults map[string]*checkrunner.ProbeResult
+
 	logHandler             *loghandler.LogHandler
 	webserver              *webserver.Server
 	checkRunner            *checkrunner.CheckRunner
 	customCheckHandler     *checkrunner.CustomCheckHandler
 	prometheusCheckHandler *checkrunner.PrometheusCheckHandler
+	probeHandler           *checkrunner.ProbeHandler
 	pushClient             *pushclient.PushClient
 }
 
 func (a *AgentInstance) processCheckResult(result map[string]interface{}) {
-	if a.customCheckResults == nil {
-		result["customchecks"] = map[string]interface{}{}
+	if a.customCheckResults == nil || a.customCheckConfiguration == nil {
+		result["customchecks"] = map[string]*utils.CommandResult{}
 	} else {
 		// Merge custom check results into "normal" check results
-		result["customchecks"] = a.customCheckResults
+		cfg := a.customCheckConfiguration
+		result["customchecks"] = checkrunner.MarkStaleCustomCheckResults(
+			a.customCheckResults,
+			cfg.CustomCheckConfiguration,
+			cfg.CustomchecksStaleFactor,
+			cfg.CustomchecksStaleUnknown,
+			time.Now(),
+		)
+	}
+
+	// Merge probe results into "normal" check results
+	probeResults := make(map[string]*checkrunner.ProbeResult, len(a.probeResults))
+	for name, probeResult := range a.probeResults {
+		probeResults[name] = probeResult
 	}
+	result["probes"] = probeResults
 
 	prometheus_results_data := make(map[string]string, len(a.prometheusExporterResults))
 	if a.prometheusExporterResults == nil {
@@ -179,19 +201,87 @@ func (a *AgentInstance) doReload(ctx context.Context, cfg *config.Configuration)
 			log.Fatalln("Could not load push client: ", err)
 		}
 	}
-	a.doCustomCheckReload(ctx, cfg.CustomCheckConfiguration)
+	a.doCustomCheckReload(ctx, cfg)
 	a.doPrometheusExporterCheckReload(ctx, cfg.PrometheusExporterConfiguration)
+	a.doProbeReload(ctx, cfg.ProbeConfiguration)
 }
 
-func (a *AgentInstance) doCustomCheckReload(ctx context.Context, ccc []*config.CustomCheck) {
+// customCheckStateFlushInterval is the interval to write new custom check results to the state file
+const customCheckStateFlushInterval = 30 * time.Second
+
+// loadCustomCheckState restores the last custom check results from the state file
+// Results already known from the running agent always take precedence over the state file
+func (a *AgentInstance) loadCustomCheckState(stateFile string) {
+	if stateFile == a.customCheckStateFile {
+		return
+	}
+	// write pending results to the previous state file
+	a.flushCustomCheckState()
+	a.customCheckStateFile = stateFile
+
+	results, err := checkrunner.LoadCustomCheckResults(stateFile)
+	if err != nil {
+		log.Errorln("Custom check state: ", err)
+		return
+	}
+	for name, result := range results {
+		if _, ok := a.customCheckResults[name]; !ok {
+			a.customCheckResults[name] = result
+		}
+	}
+}
+
+func (a *AgentInstance) saveCustomCheckState() {
+	a.customCheckStateDirty = false
+	if err := checkrunner.SaveCustomCheckResults(a.customCheckStateFile, a.customCheckResults); err != nil {
+		log.Errorln("Custom check state: ", err)
+	}
+}
+
+// flushCustomCheckState writes the custom check results to the state file if a result changed since the last write
+func (a *AgentInstance) flushCustomCheckState() {
+	if a.customCheckStateDirty {
+		a.saveCustomCheckState()
+	}
+}
+
+// integrityPolicy creates the policy for custom check executables
+// If the policy can not be loaded, no custom check will be executed at all
+func integrityPolicy(cfg *config.Configuration) *utils.IntegrityPolicy {
+	if cfg.Integrity == nil || !cfg.Integrity.Enable {
+		return nil
+	}
+	policy, err := utils.NewIntegrityPolicy(cfg.Integrity.AllowedDirectories, cfg.Integrity.HashesFile, cfg.Integrity.PublicKeyFile)
+	if err != nil {
+		log.Errorln("Custom check integrity: ", err)
+		log.Errorln("Custom check integrity: refusing to execute any custom check")
+		return &utils.IntegrityPolicy{}
+	}
+	return policy
+}
+
+func (a *AgentInstance) doCustomCheckReload(ctx context.Context, cfg *config.Configuration) {
 	if a.customCheckHandler != nil {
 		a.customCheckHandler.Shutdown()
 		a.customCheckHandler = nil
 	}
-	if len(ccc) > 0 {
+	a.customCheckConfiguration = cfg
+	a.loadCustomCheckState(cfg.CustomchecksStateFile)
+	if checkrunner.PruneCustomCheckResults(a.customCheckResults, cfg.CustomCheckConfiguration) {
+		a.saveCustomCheckState()
+	}
+	if len(cfg.CustomCheckConfiguration) > 0 {
+		lastResults := make(map[string]*utils.CommandResult, len(a.customCheckResults))
+		for name, result := range a.customCheckResults {
+			lastResults[name] = result
+		}
 		a.customCheckHandler = &checkrunner.CustomCheckHandler{
-			Configuration: ccc,
+			Configuration: cfg.CustomCheckConfiguration,
 			ResultOutput:  a.customCheckResultChan,
+			MaxConcurrent: cfg.CustomchecksMaxConcurrent,
+			Splay:         cfg.CustomchecksSplay,
+			LastResults:   lastResults,
+			Integrity:     integrityPolicy(cfg),
 		}
 		a.customCheckHandler.Start(ctx)
 	}
@@ -211,6 +301,32 @@ func (a *AgentInstance) doPrometheusExporterCheckReload(ctx context.Context, exp
 	}
 }
 
+func (a *AgentInstance) doProbeReload(ctx context.Context, probes []*config.Probe) {
+	if a.probeHandler != nil {
+		a.probeHandler.Shutdown()
+		a.probeHandler = nil
+	}
+
+	// remove results of probes which are not configured anymore
+	configured := make(map[string]bool, len(probes))
+	for _, probe := range probes {
+		configured[probe.Name] = true
+	}
+	for name := range a.probeResults {
+		if !configured[name] {
+			delete(a.probeResults, name)
+		}
+	}
+
+	if len(probes) > 0 {
+		a.probeHandler = &checkrunner.ProbeHandler{
+			Configuration: probes,
+			ResultOutput:  a.probeResultChan,
+		}
+		a.probeHandler.Start(ctx)
+	}
+}
+
 func (a *AgentInstance) stop() {
 	wg := sync.WaitGroup{}
 	if a.logHandler != nil {
@@ -237,6 +353,14 @@ func (a *AgentInstance) stop() {
 			wg.Done()
 		}()
 	}
+	if a.probeHandler != nil {
+		wg.Add(1)
+		go func() {
+			a.probeHandler.Shutdown()
+			a.probeHandler = nil
+			wg.Done()
+		}()
+	}
 	if a.checkRunner != nil {
 		wg.Add(1)
 		go func() {
@@ -261,9 +385,11 @@ func (a *AgentInstance) Start(parent context.Context) {
 	a.statePushClient = make(chan []byte)
 	a.checkResult = make(chan map[string]interface{})
 	a.customCheckResultChan = make(chan *checkrunner.CustomCheckResult)
-	a.customCheckResults = map[string]interface{}{}
+	a.customCheckResults = map[string]*utils.CommandResult{}
 	a.prometheusExporterResultChan = make(chan *checkrunner.PrometheusExporterResult)
 	a.prometheusExporterResults = make(map[string]string)
+	a.probeResultChan = make(chan *checkrunner.ProbeResultMessage)
+	a.probeResults = map[string]*checkrunner.ProbeResult{}
 	a.shutdown = make(chan struct{})
 	a.reload = make(chan chan struct{})
 	a.logHandler = &loghandler.LogHandler{
@@ -284,8 +410,14 @@ func (a *AgentInstance) Start(parent context.Context) {
 
 		a.logHandler.Start(ctx)
 
+		// write the pending custom check results after everything was stopped
+		defer a.flushCustomCheckState()
 		defer a.stop()
 
+		// the state file does not get written for every single custom check result
+		stateTicker := time.NewTicker(customCheckStateFlushInterval)
+		defer stateTicker.Stop()
+
 		for {
 			select {
 			case <-ctx.Done():
@@ -311,9 +443,15 @@ func (a *AgentInstance) Start(parent context.Context) {
 			case res := <-a.customCheckResultChan:
 				// received check result from customcheckhandler
 				a.customCheckResults[res.Name] = res.Result
+				a.customCheckStateDirty = true
+			case <-stateTicker.C:
+				a.flushCustomCheckState()
 			case res := <-a.prometheusExporterResultChan:
 				// received check result from prometheus exporter
 				a.prometheusExporterResults[res.Name] = res.Result
+			case res := <-a.probeResultChan:
+				// received probe result from probehandler
+				a.probeResults[res.Name] = res.Result
 			}
 
 		}
@@ -60,7 +60,9 @@ command = "sleep 120"
 func writeTestConfig(t *testing.T, tempDir, config, cccLin, cccWin string) {
 	cfgPath := filepath.Join(tempDir, "config.ini")
 	cccPath := filepath.Join(tempDir, "customchecks.ini")
-	if err := os.WriteFile(cfgPath, []byte(fmt.Sprintf(config, dynamicPort(), cccPath)), 0600); err != nil {
+	statePath := filepath.Join(tempDir, "customchecks_state.json")
+	config = fmt.Sprintf(config, dynamicPort(), cccPath) + fmt.Sprintf("customchecks-state = \"%s\"\n", statePath)
+	if err := os.WriteFile(cfgPath, []byte(config), 0600); err != nil {
 		t.Fatal(err)
 	}
 	cccConfig := cccLin
@@ -79,6 +79,22 @@ if [ -f /Applications/openitcockpit-agent/openitcockpit-agent ]; then
         cp /Applications/openitcockpit-agent/prometheus_exporters.ini.old /Applications/openitcockpit-agent/prometheus_exporters.ini
     fi
 
+    if [ -f /Applications/openitcockpit-agent/probes.ini.old ]; then
+        cp /Applications/openitcockpit-agent/probes.ini.old /Applications/openitcockpit-agent/probes.ini
+    fi
+
+    if [ -f /Applications/openitcockpit-agent/logfiles.ini.old ]; then
+        cp /Applications/openitcockpit-agent/logfiles.ini.old /Applications/openitcockpit-agent/logfiles.ini
+    fi
+
+    if [ -f /Applications/openitcockpit-agent/processgroups.ini.old ]; then
+        cp /Applications/openitcockpit-agent/processgroups.ini.old /Applications/openitcockpit-agent/processgroups.ini
+    fi
+
+    if [ -f /Applications/openitcockpit-agent/filestats.ini.old ]; then
+        cp /Applications/openitcockpit-agent/filestats.ini.old /Applications/openitcockpit-agent/filestats.ini
+    fi
+
     if [ "$enableConfig" == "1" ]; then
         /bin/launchctl load /Library/LaunchDaemons/com.it-novum.openitcockpit.agent.plist
     fi
@@ -80,5 +80,21 @@ if [ -f /Applications/openitcockpit-agent/openitcockpit-agent ]; then
     if [ -f /Applications/openitcockpit-agent/prometheus_exporters.ini ]; then
         cp /Applications/openitcockpit-agent/prometheus_exporters.ini /Applications/openitcockpit-agent/prometheus_exporters.ini.old
     fi
+
+    if [ -f /Applications/openitcockpit-agent/probes.ini ]; then
+        cp /Applications/openitcockpit-agent/probes.ini /Applications/openitcockpit-agent/probes.ini.old
+    fi
+
+    if [ -f /Applications/openitcockpit-agent/logfiles.ini ]; then
+        cp /Applications/openitcockpit-agent/logfiles.ini /Applications/openitcockpit-agent/logfiles.ini.old
+    fi
+
+    if [ -f /Applications/openitcockpit-agent/processgroups.ini ]; then
+        cp /Applications/openitcockpit-agent/processgroups.ini /Applications/openitcockpit-agent/processgroups.ini.old
+    fi
+
+    if [ -f /Applications/openitcockpit-agent/filestats.ini ]; then
+        cp /Applications/openitcockpit-agent/filestats.ini /Applications/openitcockpit-agent/filestats.ini.old
+    fi
     
 fi
@@ -71,5 +71,5 @@ if [ -f /Applications/openitcockpit-agent/openitcockpit-agent ]; then
         rm -rf /Library/Logs/openitcockpit-agent
     fi
 
-    rm -rf /Applications/openitcockpit-agent/com.it-novum.openitcockpit.agent.plist /Library/LaunchDaemons/com.it-novum.openitcockpit.agent.plist /Applications/openitcockpit-agent/config.ini /Applications/openitcockpit-agent/customchecks.ini /Applications/openitcockpit-agent/prometheus_exporters.ini /Applications/openitcockpit-agent /private/etc/openitcockpit-agent
+    rm -rf /Applications/openitcockpit-agent/com.it-novum.openitcockpit.agent.plist /Library/LaunchDaemons/com.it-novum.openitcockpit.agent.plist /Applications/openitcockpit-agent/config.ini /Applications/openitcockpit-agent/customchecks.ini /Applications/openitcockpit-agent/prometheus_exporters.ini /Applications/openitcockpit-agent/probes.ini /Applications/openitcockpit-agent/logfiles.ini /Applications/openitcockpit-agent/logfiles_state.json /Applications/openitcockpit-agent/processgroups.ini /Applications/openitcockpit-agent/filestats.ini /Applications/openitcockpit-agent /private/etc/openitcockpit-agent
 fi
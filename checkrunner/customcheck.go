@@ -11,12 +11,29 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// State types of custom check results
+const (
+	StateTypeSoft = "soft"
+	StateTypeHard = "hard"
+)
+
 type CustomCheckExecutor struct {
 	Configuration *config.CustomCheck
 	ResultOutput  chan *CustomCheckResult
+	// StartDelay postpones the first execution of the check (splay)
+	StartDelay time.Duration
+	// Limiter is shared by all executors to limit the number of concurrently running checks (nil = unlimited)
+	Limiter chan struct{}
+	// LastResult of a previous run to continue the attempt counter after a reload
+	LastResult *utils.CommandResult
+	// Integrity policy to verify the executable before each execution (nil = disabled)
+	Integrity *utils.IntegrityPolicy
 
-	wg       sync.WaitGroup
-	shutdown chan struct{}
+	wg        sync.WaitGroup
+	shutdown  chan struct{}
+	attempt   int64
+	stateType string
+	lastRC    int
 }
 
 func (c *CustomCheckExecutor) Shutdown() {
@@ -24,17 +41,109 @@ func (c *CustomCheckExecutor) Shutdown() {
 	c.wg.Wait()
 }
 
+// acquire a slot from the limiter, returns false if the executor was stopped while waiting
+func (c *CustomCheckExecutor) acquire(ctx context.Context) bool {
+	if c.Limiter == nil {
+		return true
+	}
+	select {
+	case c.Limiter <- struct{}{}:
+		return true
+	case <-c.shutdown:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (c *CustomCheckExecutor) release() {
+	if c.Limiter != nil {
+		<-c.Limiter
+	}
+}
+
+func (c *CustomCheckExecutor) maxAttempts() int64 {
+	if c.Configuration.MaxAttempts < 1 {
+		return 1
+	}
+	return c.Configuration.MaxAttempts
+}
+
+// initState restores the attempt counter and state type from the last result
+func (c *CustomCheckExecutor) initState() {
+	c.attempt = 1
+	c.stateType = StateTypeHard
+	c.lastRC = utils.Ok
+
+	if c.LastResult != nil && c.LastResult.StateType != "" {
+		c.attempt = c.LastResult.CurrentAttempt
+		c.stateType = c.LastResult.StateType
+		c.lastRC = c.LastResult.RC
+	}
+}
+
+// updateState calculates the attempt counter and the soft/hard state of the result
+// A non-OK result will only become a hard state after MaxAttempts consecutive non-OK results
+func (c *CustomCheckExecutor) updateState(result *utils.CommandResult) {
+	maxAttempts := c.maxAttempts()
+
+	switch {
+	case result.RC == utils.Ok:
+		c.attempt = 1
+	case c.lastRC == utils.Ok:
+		// first non-OK result
+		c.attempt = 1
+	case c.stateType == StateTypeSoft:
+		c.attempt++
+	}
+
+	if result.RC == utils.Ok || c.attempt >= maxAttempts {
+		c.stateType = StateTypeHard
+	} else {
+		c.stateType = StateTypeSoft
+	}
+	if c.attempt > maxAttempts {
+		c.attempt = maxAttempts
+	}
+	c.lastRC = result.RC
+
+	result.CurrentAttempt = c.attempt
+	result.MaxAttempts = maxAttempts
+	result.StateType = c.stateType
+}
+
+// nextInterval returns the retry interval while the check is in a non-OK soft state
+func (c *CustomCheckExecutor) nextInterval() time.Duration {
+	if c.stateType == StateTypeSoft && c.lastRC != utils.Ok && c.Configuration.RetryInterval > 0 {
+		return time.Duration(c.Configuration.RetryInterval) * time.Second
+	}
+	return time.Duration(c.Configuration.Interval) * time.Second
+}
+
 func (c *CustomCheckExecutor) runCheck(ctx context.Context, timeout time.Duration) {
+	if !c.acquire(ctx) {
+		log.Debugln("CustomCheck: canceled while waiting in queue: ", c.Configuration.Name)
+		return
+	}
 	log.Debugln("Begin CustomCheck: ", c.Configuration.Name)
 	result, err := utils.RunCommand(ctx, utils.CommandArgs{
 		Command:       c.Configuration.Command,
 		Timeout:       timeout,
 		Shell:         c.Configuration.Shell,
 		PowershellExe: c.Configuration.PowershellExe,
+		Integrity:     c.Integrity,
 	})
-	if err != nil && result.RC == utils.Unknown {
+	c.release()
+	var integrityErr *utils.IntegrityError
+	if errors.As(err, &integrityErr) {
+		log.Errorln("Custom check '", c.Configuration.Name, "' was not executed: ", err)
+	} else if err != nil && result.RC == utils.Unknown {
 		log.Infoln("Custom check '", c.Configuration.Name, "' error: ", err)
 	}
+	c.updateState(result)
+	if result.StateType == StateTypeSoft {
+		log.Debugln("CustomCheck: ", c.Configuration.Name, " soft state attempt ", result.CurrentAttempt, "/", result.MaxAttempts)
+	}
 	select {
 	// Return custom check result to Agent Instance
 	case c.ResultOutput <- &CustomCheckResult{
@@ -61,6 +170,10 @@ func (c *CustomCheckExecutor) Start(parent context.Context) error {
 	if timeout > interval {
 		return errors.New("custom check timeout must be lower or equal to interval")
 	}
+	if c.Configuration.RetryInterval > 0 && timeout > time.Duration(c.Configuration.RetryInterval)*time.Second {
+		return errors.New("custom check timeout must be lower or equal to retry interval")
+	}
+	c.initState()
 
 	c.wg.Add(1)
 	go func() {
@@ -69,10 +182,26 @@ func (c *CustomCheckExecutor) Start(parent context.Context) error {
 		ctx, cancel := context.WithCancel(parent)
 		defer cancel()
 
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
+		if c.StartDelay > 0 {
+			log.Debugln("CustomCheck: delay first execution of ", c.Configuration.Name, " by ", c.StartDelay)
+			delay := time.NewTimer(c.StartDelay)
+			select {
+			case <-ctx.Done():
+				delay.Stop()
+				return
+			case <-c.shutdown:
+				delay.Stop()
+				return
+			case <-delay.C:
+			}
+		}
 
+		// the next execution depends on the result (retry interval), so we can not use a ticker
+		started := time.Now()
 		c.runCheck(ctx, timeout)
+		timer := time.NewTimer(time.Until(started.Add(c.nextInterval())))
+		defer timer.Stop()
+
 		for {
 			select {
 			case <-ctx.Done():
@@ -81,8 +210,10 @@ func (c *CustomCheckExecutor) Start(parent context.Context) error {
 				if !ok {
 					return
 				}
-			case <-ticker.C:
+			case <-timer.C:
+				started = time.Now()
 				c.runCheck(ctx, timeout)
+				timer.Reset(time.Until(started.Add(c.nextInterval())))
 			}
 		}
 	}()
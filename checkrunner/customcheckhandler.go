@@ -2,7 +2,9 @@ package checkrunner
 
 import (
 	"context"
+	"hash/fnv"
 	"sync"
+	"time"
 
 	"github.com/it-novum/openitcockpit-agent-go/config"
 	"github.com/it-novum/openitcockpit-agent-go/utils"
@@ -20,6 +22,15 @@ type CustomCheckHandler struct {
 	// Do not close before Shutdown completes
 	ResultOutput  chan *CustomCheckResult
 	Configuration []*config.CustomCheck
+	// MaxConcurrent limits the number of custom checks running at the same time, further checks wait in a queue (0 = unlimited)
+	MaxConcurrent int64
+	// Splay spreads the first execution of the checks across their interval
+	Splay bool
+	// LastResults of the custom checks from a previous run or the state file
+	// A check with a result that is still fresh for its interval will not be executed immediately
+	LastResults map[string]*utils.CommandResult
+	// Integrity policy which all custom check executables have to pass (nil = disabled)
+	Integrity *utils.IntegrityPolicy
 
 	executors []*CustomCheckExecutor
 	shutdown  chan struct{}
@@ -52,15 +63,56 @@ func (c *CustomCheckHandler) stopExecutors() {
 	close(stopC)
 }
 
+// splayOffset returns a deterministic start offset for the check within its interval,
+// so the checks do not all start at the same time but keep their position after every reload
+func splayOffset(name string, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// startDelay returns how long the first execution of the check should be postponed
+// If the last result is still fresh for the check interval, the check will run when the result would expire
+func startDelay(checkConfig *config.CustomCheck, last *utils.CommandResult, splay bool, now time.Time) time.Duration {
+	interval := time.Duration(checkConfig.Interval) * time.Second
+	if last != nil && last.StateType == StateTypeSoft && last.RC != utils.Ok && checkConfig.RetryInterval > 0 {
+		// the check was waiting for a retry
+		interval = time.Duration(checkConfig.RetryInterval) * time.Second
+	}
+	if last != nil && last.ExecutionUnixTimestampSec > 0 {
+		age := now.Sub(time.Unix(last.ExecutionUnixTimestampSec, 0))
+		if age >= 0 && age < interval {
+			return interval - age
+		}
+	}
+	if splay {
+		return splayOffset(checkConfig.Name, interval)
+	}
+	return 0
+}
+
 // Run the custom checks in background (DO NOT RUN IN GO ROUTINE)
 func (c *CustomCheckHandler) Start(parentCtx context.Context) {
 	c.shutdown = make(chan struct{})
 	c.executors = make([]*CustomCheckExecutor, len(c.Configuration))
 
+	var limiter chan struct{}
+	if c.MaxConcurrent > 0 {
+		limiter = make(chan struct{}, c.MaxConcurrent)
+	}
+
+	now := time.Now()
 	for i, checkConfig := range c.Configuration {
 		c.executors[i] = &CustomCheckExecutor{
 			Configuration: checkConfig,
 			ResultOutput:  c.ResultOutput,
+			Limiter:       limiter,
+			StartDelay:    startDelay(checkConfig, c.LastResults[checkConfig.Name], c.Splay, now),
+			LastResult:    c.LastResults[checkConfig.Name],
+			Integrity:     c.Integrity,
 		}
 	}
 
@@ -61,3 +61,130 @@ outerfor:
 	}
 
 }
+
+func TestSplayOffset(t *testing.T) {
+	interval := 60 * time.Second
+
+	first := splayOffset("check_1", interval)
+	if first < 0 || first >= interval {
+		t.Fatal("splay offset out of interval: ", first)
+	}
+	if first != splayOffset("check_1", interval) {
+		t.Fatal("splay offset is not deterministic")
+	}
+	if splayOffset("check_1", 0) != 0 {
+		t.Fatal("expected no splay offset for empty interval")
+	}
+}
+
+func TestRunMaxConcurrent(t *testing.T) {
+	cc := &CustomCheckHandler{
+		ResultOutput:  make(chan *CustomCheckResult),
+		MaxConcurrent: 1,
+		Configuration: []*config.CustomCheck{
+			{
+				Name:     "check_1",
+				Interval: 10,
+				Enabled:  true,
+				Timeout:  1,
+				Command:  getCommandLine(),
+			},
+			{
+				Name:     "check_2",
+				Interval: 10,
+				Enabled:  true,
+				Timeout:  1,
+				Command:  getCommandLine(),
+			},
+		},
+	}
+	cc.Start(context.Background())
+
+	results := map[string]*utils.CommandResult{}
+	timeout := time.After(time.Second * 5)
+
+	for len(results) < 2 {
+		select {
+		case <-timeout:
+			t.Fatal("timeout waiting for queued custom checks: ", len(results))
+		case res := <-cc.ResultOutput:
+			results[res.Name] = res.Result
+		}
+	}
+
+	go func() {
+		cc.Shutdown()
+		close(cc.ResultOutput)
+	}()
+	for range cc.ResultOutput {
+	}
+}
+
+func TestStartDelay(t *testing.T) {
+	// results only store the execution time in seconds
+	now := time.Unix(time.Now().Unix(), 0)
+	checkConfig := &config.CustomCheck{
+		Name:     "check_backup",
+		Interval: 3600,
+	}
+
+	if d := startDelay(checkConfig, nil, false, now); d != 0 {
+		t.Fatal("expected immediate execution without cached result: ", d)
+	}
+
+	fresh := &utils.CommandResult{
+		ExecutionUnixTimestampSec: now.Add(-10 * time.Minute).Unix(),
+	}
+	if d := startDelay(checkConfig, fresh, false, now); d != 50*time.Minute {
+		t.Fatal("expected execution when the cached result expires: ", d)
+	}
+
+	expired := &utils.CommandResult{
+		ExecutionUnixTimestampSec: now.Add(-2 * time.Hour).Unix(),
+	}
+	if d := startDelay(checkConfig, expired, false, now); d != 0 {
+		t.Fatal("expected immediate execution for expired cached result: ", d)
+	}
+	if d := startDelay(checkConfig, expired, true, now); d != splayOffset(checkConfig.Name, time.Hour) {
+		t.Fatal("expected splay offset for expired cached result: ", d)
+	}
+}
+
+func TestCustomCheckSoftState(t *testing.T) {
+	executor := &CustomCheckExecutor{
+		Configuration: &config.CustomCheck{
+			Name:          "check_flaky",
+			Interval:      60,
+			RetryInterval: 10,
+			MaxAttempts:   3,
+		},
+	}
+	executor.initState()
+
+	expected := []struct {
+		rc        int
+		attempt   int64
+		stateType string
+		interval  time.Duration
+	}{
+		{utils.Ok, 1, StateTypeHard, 60 * time.Second},
+		{utils.Critical, 1, StateTypeSoft, 10 * time.Second},
+		{utils.Critical, 2, StateTypeSoft, 10 * time.Second},
+		{utils.Critical, 3, StateTypeHard, 60 * time.Second},
+		{utils.Warning, 3, StateTypeHard, 60 * time.Second},
+		{utils.Ok, 1, StateTypeHard, 60 * time.Second},
+		{utils.Critical, 1, StateTypeSoft, 10 * time.Second},
+		{utils.Ok, 1, StateTypeHard, 60 * time.Second},
+	}
+
+	for i, e := range expected {
+		result := &utils.CommandResult{RC: e.rc}
+		executor.updateState(result)
+		if result.CurrentAttempt != e.attempt || result.StateType != e.stateType || result.MaxAttempts != 3 {
+			t.Fatalf("step %d: unexpected state %d/%d %s", i, result.CurrentAttempt, result.MaxAttempts, result.StateType)
+		}
+		if interval := executor.nextInterval(); interval != e.interval {
+			t.Fatalf("step %d: unexpected next interval %s", i, interval)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package checkrunner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+)
+
+// LoadCustomCheckResults reads the last custom check results from the state file
+// A missing state file is not an error and results in an empty map
+func LoadCustomCheckResults(path string) (map[string]*utils.CommandResult, error) {
+	results := map[string]*utils.CommandResult{}
+	if path == "" || utils.FileNotExists(path) {
+		return results, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return results, fmt.Errorf("could not read custom check state file: %s", err)
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return map[string]*utils.CommandResult{}, fmt.Errorf("could not parse custom check state file: %s", err)
+	}
+
+	for name, result := range results {
+		if result == nil {
+			delete(results, name)
+		}
+	}
+	return results, nil
+}
+
+// SaveCustomCheckResults writes the last custom check results to the state file
+func SaveCustomCheckResults(path string, results map[string]*utils.CommandResult) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("could not serialize custom check state: %s", err)
+	}
+
+	// write to a temporary file first, so we never leave a partially written state file behind
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("could not write custom check state file: %s", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("could not write custom check state file: %s", err)
+	}
+	return nil
+}
+
+// PruneCustomCheckResults removes the results of all custom checks which are not configured (or disabled) anymore
+// Returns true if any result was removed
+func PruneCustomCheckResults(results map[string]*utils.CommandResult, checks []*config.CustomCheck) bool {
+	configured := make(map[string]bool, len(checks))
+	for _, check := range checks {
+		configured[check.Name] = true
+	}
+
+	pruned := false
+	for name := range results {
+		if !configured[name] {
+			delete(results, name)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// MarkStaleCustomCheckResults returns a copy of the results where every result older than staleFactor * interval is marked as stale
+// If unknown is true a stale result will be reported as UNKNOWN
+func MarkStaleCustomCheckResults(results map[string]*utils.CommandResult, checks []*config.CustomCheck, staleFactor int64, unknown bool, now time.Time) map[string]*utils.CommandResult {
+	intervals := make(map[string]int64, len(checks))
+	for _, check := range checks {
+		intervals[check.Name] = check.Interval
+	}
+
+	marked := make(map[string]*utils.CommandResult, len(results))
+	for name, result := range results {
+		interval, ok := intervals[name]
+		if staleFactor <= 0 || !ok || interval <= 0 {
+			marked[name] = result
+			continue
+		}
+
+		age := now.Sub(time.Unix(result.ExecutionUnixTimestampSec, 0))
+		if age <= time.Duration(staleFactor*interval)*time.Second {
+			marked[name] = result
+			continue
+		}
+
+		staleResult := *result
+		staleResult.Stale = true
+		if unknown {
+			staleResult.RC = utils.Unknown
+			staleResult.Stdout = fmt.Sprintf("UNKNOWN - Result of custom check %s is stale, last execution was %s ago\n%s", name, age.Truncate(time.Second), result.Stdout)
+		}
+		marked[name] = &staleResult
+	}
+	return marked
+}
@@ -0,0 +1,128 @@
+package checkrunner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+)
+
+func TestCustomCheckResultsSaveLoad(t *testing.T) {
+	tempDir, err := os.MkdirTemp(os.TempDir(), "*-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	stateFile := filepath.Join(tempDir, "customchecks_state.json")
+
+	results, err := LoadCustomCheckResults(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatal("expected empty results for missing state file")
+	}
+
+	results["check_1"] = &utils.CommandResult{
+		Stdout:                    "OK - backup verified",
+		RC:                        utils.Ok,
+		ExecutionUnixTimestampSec: 1600000000,
+	}
+	if err := SaveCustomCheckResults(stateFile, results); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadCustomCheckResults(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, ok := loaded["check_1"]
+	if !ok {
+		t.Fatal("check_1 missing in state file")
+	}
+	if res.Stdout != "OK - backup verified" || res.RC != utils.Ok || res.ExecutionUnixTimestampSec != 1600000000 {
+		t.Fatal("unexpected result from state file: ", res)
+	}
+}
+
+func TestCustomCheckResultsLoadInvalid(t *testing.T) {
+	tempDir, err := os.MkdirTemp(os.TempDir(), "*-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	stateFile := filepath.Join(tempDir, "customchecks_state.json")
+	if err := os.WriteFile(stateFile, []byte("{invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := LoadCustomCheckResults(stateFile)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if results == nil || len(results) != 0 {
+		t.Fatal("expected empty results for invalid state file")
+	}
+}
+
+func TestPruneCustomCheckResults(t *testing.T) {
+	results := map[string]*utils.CommandResult{
+		"check_1":       {RC: utils.Ok},
+		"check_removed": {RC: utils.Critical},
+	}
+	checks := []*config.CustomCheck{
+		{Name: "check_1", Interval: 60},
+	}
+
+	if !PruneCustomCheckResults(results, checks) {
+		t.Fatal("expected removed custom check to be pruned")
+	}
+	if _, ok := results["check_removed"]; ok {
+		t.Fatal("result of removed custom check still exists")
+	}
+	if _, ok := results["check_1"]; !ok {
+		t.Fatal("result of configured custom check was removed")
+	}
+	if PruneCustomCheckResults(results, checks) {
+		t.Fatal("expected nothing to prune")
+	}
+}
+
+func TestMarkStaleCustomCheckResults(t *testing.T) {
+	now := time.Now()
+	results := map[string]*utils.CommandResult{
+		"check_fresh": {Stdout: "OK", RC: utils.Ok, ExecutionUnixTimestampSec: now.Add(-time.Minute).Unix()},
+		"check_stale": {Stdout: "OK", RC: utils.Ok, ExecutionUnixTimestampSec: now.Add(-time.Hour).Unix()},
+	}
+	checks := []*config.CustomCheck{
+		{Name: "check_fresh", Interval: 60},
+		{Name: "check_stale", Interval: 60},
+	}
+
+	marked := MarkStaleCustomCheckResults(results, checks, 3, false, now)
+	if marked["check_fresh"].Stale {
+		t.Fatal("fresh result marked as stale")
+	}
+	if !marked["check_stale"].Stale || marked["check_stale"].RC != utils.Ok {
+		t.Fatal("expected stale result with original rc: ", marked["check_stale"])
+	}
+	if results["check_stale"].Stale {
+		t.Fatal("original result must not be modified")
+	}
+
+	marked = MarkStaleCustomCheckResults(results, checks, 3, true, now)
+	if marked["check_stale"].RC != utils.Unknown || !strings.HasPrefix(marked["check_stale"].Stdout, "UNKNOWN") {
+		t.Fatal("expected stale result to be unknown: ", marked["check_stale"])
+	}
+
+	marked = MarkStaleCustomCheckResults(results, checks, 0, true, now)
+	if marked["check_stale"].Stale {
+		t.Fatal("stale detection should be disabled")
+	}
+}
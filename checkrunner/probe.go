@@ -0,0 +1,338 @@
+package checkrunner
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxProbeBodySize limits the number of bytes read from HTTP responses and TCP banners
+const maxProbeBodySize = 1024 * 1024
+
+// ProbeResult of a synthetic probe
+type ProbeResult struct {
+	Type   string `json:"type"`
+	Target string `json:"target"` // URL, address or query
+	// RC is Ok, Warning (latency) or Critical
+	RC      int    `json:"rc"`
+	Message string `json:"message"`
+	// Latency in milliseconds
+	Latency                   float64  `json:"latency"`
+	StatusCode                int      `json:"status_code,omitempty"`
+	Banner                    string   `json:"banner,omitempty"`
+	Answers                   []string `json:"answers,omitempty"`
+	ExecutionUnixTimestampSec int64    `json:"execution_unix_timestamp_sec"`
+}
+
+type ProbeExecutor struct {
+	Configuration *config.Probe
+	ResultOutput  chan *ProbeResultMessage
+
+	wg       sync.WaitGroup
+	shutdown chan struct{}
+}
+
+func (c *ProbeExecutor) Shutdown() {
+	close(c.shutdown)
+	c.wg.Wait()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// checkLatency sets the RC of a successful probe according to the latency thresholds
+func checkLatency(probe *config.Probe, result *ProbeResult) {
+	switch {
+	case probe.CriticalLatency > 0 && result.Latency > float64(probe.CriticalLatency):
+		result.RC = utils.Critical
+		result.Message += fmt.Sprintf(" (latency %.0fms > %dms)", result.Latency, probe.CriticalLatency)
+	case probe.WarningLatency > 0 && result.Latency > float64(probe.WarningLatency):
+		result.RC = utils.Warning
+		result.Message += fmt.Sprintf(" (latency %.0fms > %dms)", result.Latency, probe.WarningLatency)
+	}
+}
+
+func probeHTTP(ctx context.Context, probe *config.Probe, result *ProbeResult) error {
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DisableKeepAlives: true,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: probe.Insecure,
+			},
+		},
+		// Redirects are not followed, so the status code can be checked (e.g.: expected_status = 301)
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, probe.Method, probe.URL, nil)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	if err != nil {
+		return err
+	}
+	result.Latency = milliseconds(time.Since(start))
+	result.StatusCode = resp.StatusCode
+
+	expected := false
+	for _, status := range probe.ExpectedStatus {
+		if status == fmt.Sprint(resp.StatusCode) {
+			expected = true
+			break
+		}
+	}
+	if !expected {
+		return fmt.Errorf("unexpected status code %d, expected %s", resp.StatusCode, strings.Join(probe.ExpectedStatus, ","))
+	}
+	if probe.BodyRegex != "" && !regexp.MustCompile(probe.BodyRegex).Match(body) {
+		return fmt.Errorf("response body does not match '%s'", probe.BodyRegex)
+	}
+	result.Message = fmt.Sprintf("HTTP %d in %.0fms", resp.StatusCode, result.Latency)
+	return nil
+}
+
+func probeTCP(ctx context.Context, probe *config.Probe, result *ProbeResult) error {
+	dialer := &net.Dialer{}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", probe.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	result.Latency = milliseconds(time.Since(start))
+
+	if probe.BannerRegex != "" {
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetReadDeadline(deadline)
+		}
+		regex := regexp.MustCompile(probe.BannerRegex)
+		banner := []byte{}
+		buf := make([]byte, 4096)
+		// read until the banner matches, the connection was closed or the timeout was reached
+		for !regex.Match(banner) {
+			n, err := conn.Read(buf)
+			banner = append(banner, buf[:n]...)
+			if err != nil || len(banner) >= maxProbeBodySize {
+				result.Banner = strings.TrimSpace(string(banner))
+				return fmt.Errorf("banner does not match '%s'", probe.BannerRegex)
+			}
+		}
+		result.Banner = strings.TrimSpace(string(banner))
+		result.Latency = milliseconds(time.Since(start))
+	}
+	result.Message = fmt.Sprintf("TCP connect to %s in %.0fms", probe.Address, result.Latency)
+	return nil
+}
+
+// lookupDNS queries the record type and returns all answers as string
+func lookupDNS(ctx context.Context, resolver *net.Resolver, recordType, query string) ([]string, error) {
+	answers := []string{}
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		mxs, err := resolver.LookupMX(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case "NS":
+		nss, err := resolver.LookupNS(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			answers = append(answers, ns.Host)
+		}
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, txts...)
+	default:
+		return nil, fmt.Errorf("unsupported record type %s", recordType)
+	}
+	return answers, nil
+}
+
+func probeDNS(ctx context.Context, probe *config.Probe, result *ProbeResult) error {
+	resolver := net.DefaultResolver
+	if probe.Resolver != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				dialer := &net.Dialer{}
+				return dialer.DialContext(ctx, network, probe.Resolver)
+			},
+		}
+	}
+
+	start := time.Now()
+	answers, err := lookupDNS(ctx, resolver, probe.RecordType, probe.Query)
+	if err != nil {
+		return err
+	}
+	result.Latency = milliseconds(time.Since(start))
+	result.Answers = answers
+	if len(answers) == 0 {
+		return fmt.Errorf("no %s record found for %s", probe.RecordType, probe.Query)
+	}
+
+	if probe.AnswerRegex != "" {
+		regex := regexp.MustCompile(probe.AnswerRegex)
+		matched := false
+		for _, answer := range answers {
+			if regex.MatchString(answer) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("no answer matches '%s'", probe.AnswerRegex)
+		}
+	}
+	result.Message = fmt.Sprintf("DNS %s %s: %s in %.0fms", probe.RecordType, probe.Query, strings.Join(answers, ", "), result.Latency)
+	return nil
+}
+
+// RunProbe executes the probe within the timeout, a failed probe results in a CRITICAL result
+func RunProbe(ctx context.Context, probe *config.Probe, timeout time.Duration) *ProbeResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := &ProbeResult{
+		Type:                      probe.Type,
+		RC:                        utils.Ok,
+		ExecutionUnixTimestampSec: time.Now().Unix(),
+	}
+
+	var err error
+	switch probe.Type {
+	case config.ProbeTypeHTTP:
+		result.Target = probe.URL
+		err = probeHTTP(ctx, probe, result)
+	case config.ProbeTypeTCP:
+		result.Target = probe.Address
+		err = probeTCP(ctx, probe, result)
+	case config.ProbeTypeDNS:
+		result.Target = probe.Query
+		err = probeDNS(ctx, probe, result)
+	default:
+		err = fmt.Errorf("unknown probe type: %s", probe.Type)
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timeout after %s: %s", timeout, err)
+		}
+		result.RC = utils.Critical
+		result.Message = err.Error()
+		return result
+	}
+	checkLatency(probe, result)
+	return result
+}
+
+func (c *ProbeExecutor) runProbe(ctx context.Context, timeout time.Duration) {
+	log.Debugln("Begin Probe: ", c.Configuration.Name)
+	result := RunProbe(ctx, c.Configuration, timeout)
+	if result.RC != utils.Ok {
+		log.Debugln("Probe '", c.Configuration.Name, "': ", result.Message)
+	}
+
+	select {
+	// Return probe result to Agent Instance
+	case c.ResultOutput <- &ProbeResultMessage{
+		Name:   c.Configuration.Name,
+		Result: result,
+	}:
+	case <-time.After(time.Second * 5):
+		log.Errorln("Internal error: timeout could not save probe result")
+	case <-c.shutdown:
+		log.Errorln("Probe: canceled")
+		return
+	case <-ctx.Done():
+		log.Errorln("Probe: canceled")
+		return
+	}
+	log.Debugln("Finish Probe: ", c.Configuration.Name)
+}
+
+func (c *ProbeExecutor) Start(parent context.Context) error {
+	c.shutdown = make(chan struct{})
+	timeout := time.Duration(c.Configuration.Timeout) * time.Second
+	interval := time.Duration(c.Configuration.Interval) * time.Second
+
+	if timeout > interval {
+		return errors.New("probe timeout must be lower or equal to interval")
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		c.runProbe(ctx, timeout)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-c.shutdown:
+				if !ok {
+					return
+				}
+			case <-ticker.C:
+				c.runProbe(ctx, timeout)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package checkrunner
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+)
+
+func validProbe(t *testing.T, probe *config.Probe) *config.Probe {
+	probe.Name = "test"
+	probe.Enabled = true
+	if err := config.ValidateProbe(probe); err != nil {
+		t.Fatal(err)
+	}
+	return probe
+}
+
+func TestProbeHTTP(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/health", http.StatusMovedPermanently)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer server.Close()
+
+	probe := validProbe(t, &config.Probe{
+		Type:      config.ProbeTypeHTTP,
+		URL:       server.URL + "/health",
+		BodyRegex: `"status":\s*"ok"`,
+		Insecure:  true,
+	})
+	result := RunProbe(context.Background(), probe, 5*time.Second)
+	if result.RC != utils.Ok || result.StatusCode != 200 || result.Target != probe.URL || result.Type != "http" {
+		t.Fatal("unexpected result: ", result)
+	}
+
+	probe.BodyRegex = "failed"
+	if result := RunProbe(context.Background(), probe, 5*time.Second); result.RC != utils.Critical || !strings.Contains(result.Message, "does not match") {
+		t.Fatal("expected body regex mismatch: ", result)
+	}
+
+	probe.BodyRegex = ""
+	probe.URL = server.URL + "/old"
+	if result := RunProbe(context.Background(), probe, 5*time.Second); result.RC != utils.Critical || result.StatusCode != 301 {
+		t.Fatal("expected unexpected status code: ", result)
+	}
+	probe.ExpectedStatus = []string{"301"}
+	if result := RunProbe(context.Background(), probe, 5*time.Second); result.RC != utils.Ok {
+		t.Fatal("expected redirect to be accepted: ", result)
+	}
+
+	probe.Insecure = false
+	if result := RunProbe(context.Background(), probe, 5*time.Second); result.RC != utils.Critical {
+		t.Fatal("expected certificate error: ", result)
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("220 mail.example.org ESMTP\r\n"))
+			conn.Close()
+		}
+	}()
+
+	probe := validProbe(t, &config.Probe{
+		Type:        config.ProbeTypeTCP,
+		Address:     l.Addr().String(),
+		BannerRegex: "^220 .+ESMTP",
+	})
+	result := RunProbe(context.Background(), probe, 5*time.Second)
+	if result.RC != utils.Ok || result.Banner != "220 mail.example.org ESMTP" {
+		t.Fatal("unexpected result: ", result)
+	}
+
+	probe.BannerRegex = "^554"
+	if result := RunProbe(context.Background(), probe, 5*time.Second); result.RC != utils.Critical || result.Banner == "" {
+		t.Fatal("expected banner mismatch: ", result)
+	}
+
+	l.Close()
+	probe.BannerRegex = ""
+	if result := RunProbe(context.Background(), probe, 5*time.Second); result.RC != utils.Critical {
+		t.Fatal("expected connection error: ", result)
+	}
+}
+
+// serveDNS answers every A query with 192.0.2.10
+func serveDNS(conn net.PacketConn) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		query := buf[:n]
+		// skip the labels of the question name
+		end := 12
+		for end < n && query[end] != 0 {
+			end += int(query[end]) + 1
+		}
+		end += 5
+		if end > n {
+			continue
+		}
+		qtype := int(query[end-4])<<8 | int(query[end-3])
+
+		resp := append([]byte{}, query[:2]...)
+		resp = append(resp, 0x81, 0x80, 0, 1)
+		if qtype == 1 {
+			resp = append(resp, 0, 1, 0, 0, 0, 0)
+		} else {
+			resp = append(resp, 0, 0, 0, 0, 0, 0)
+		}
+		resp = append(resp, query[12:end]...)
+		if qtype == 1 {
+			resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 10)
+		}
+		_, _ = conn.WriteTo(resp, addr)
+	}
+}
+
+func TestProbeDNS(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go serveDNS(conn)
+
+	probe := validProbe(t, &config.Probe{
+		Type:        config.ProbeTypeDNS,
+		Query:       "intranet.example.org",
+		Resolver:    conn.LocalAddr().String(),
+		AnswerRegex: `^192\.0\.2\.`,
+	})
+	result := RunProbe(context.Background(), probe, 5*time.Second)
+	if result.RC != utils.Ok || len(result.Answers) != 1 || result.Answers[0] != "192.0.2.10" {
+		t.Fatal("unexpected result: ", result)
+	}
+
+	probe.AnswerRegex = "^10\\."
+	if result := RunProbe(context.Background(), probe, 5*time.Second); result.RC != utils.Critical {
+		t.Fatal("expected answer mismatch: ", result)
+	}
+
+	probe.AnswerRegex = ""
+	probe.RecordType = "AAAA"
+	if result := RunProbe(context.Background(), probe, 5*time.Second); result.RC != utils.Critical {
+		t.Fatal("expected missing AAAA record: ", result)
+	}
+}
+
+func TestProbeLatency(t *testing.T) {
+	probe := &config.Probe{WarningLatency: 100, CriticalLatency: 500}
+	for latency, rc := range map[float64]int{50: utils.Ok, 150: utils.Warning, 600: utils.Critical} {
+		result := &ProbeResult{RC: utils.Ok, Latency: latency}
+		checkLatency(probe, result)
+		if result.RC != rc {
+			t.Error("unexpected RC for latency ", latency, ": ", result.RC)
+		}
+	}
+}
+
+func TestProbeHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ph := &ProbeHandler{
+		ResultOutput: make(chan *ProbeResultMessage),
+		Configuration: []*config.Probe{
+			validProbe(t, &config.Probe{
+				Type:     config.ProbeTypeHTTP,
+				URL:      server.URL,
+				Interval: 1,
+				Timeout:  1,
+			}),
+		},
+	}
+	ph.Start(context.Background())
+	defer ph.Shutdown()
+
+	select {
+	case res := <-ph.ResultOutput:
+		if res.Name != "test" || res.Result.RC != utils.Ok {
+			t.Fatal("unexpected result: ", res.Result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for probe result")
+	}
+}
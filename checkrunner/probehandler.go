@@ -0,0 +1,101 @@
+package checkrunner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	log "github.com/sirupsen/logrus"
+)
+
+type ProbeResultMessage struct {
+	Name   string
+	Result *ProbeResult
+}
+
+// ProbeHandler runs synthetic probes
+type ProbeHandler struct {
+	// ResultOutput channel for probe results
+	// Do not close before Shutdown completes
+	ResultOutput  chan *ProbeResultMessage
+	Configuration []*config.Probe
+
+	executors []*ProbeExecutor
+	shutdown  chan struct{}
+	wg        sync.WaitGroup
+}
+
+// stop all probe executors in parallel
+// the cancel of the context should cause all executors to stop almost immediatly
+func (c *ProbeHandler) stopExecutors() {
+	if len(c.executors) < 1 {
+		return
+	}
+
+	stopC := make(chan *ProbeExecutor)
+
+	for i := 0; i < len(c.executors); i++ {
+		go func() {
+			for e := range stopC {
+				e.Shutdown()
+				log.Infoln("Probe ", e.Configuration.Name, " stopped")
+				c.wg.Done()
+			}
+		}()
+	}
+
+	for _, executor := range c.executors {
+		stopC <- executor
+	}
+
+	close(stopC)
+}
+
+// Run the probes in background (DO NOT RUN IN GO ROUTINE)
+func (c *ProbeHandler) Start(parentCtx context.Context) {
+	c.shutdown = make(chan struct{})
+	c.executors = make([]*ProbeExecutor, len(c.Configuration))
+
+	for i, probeConfig := range c.Configuration {
+		c.executors[i] = &ProbeExecutor{
+			Configuration: probeConfig,
+			ResultOutput:  c.ResultOutput,
+		}
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ctx, cancel := context.WithCancel(parentCtx)
+		defer cancel()
+
+		for _, executor := range c.executors {
+			log.Infoln("Probe ", executor.Configuration.Name, " starting")
+			c.wg.Add(1)
+			if err := executor.Start(ctx); err != nil {
+				log.Errorln(err)
+			}
+		}
+
+		defer c.stopExecutors()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-c.shutdown:
+				if !ok {
+					return
+				}
+			}
+		}
+
+	}()
+}
+
+// Shutdown probe runner, waits for completion
+func (c *ProbeHandler) Shutdown() {
+	close(c.shutdown)
+	c.wg.Wait()
+}
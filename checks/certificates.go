@@ -0,0 +1,256 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+)
+
+// certificateEndpointTimeout is the maximum time to connect to an endpoint and finish the TLS handshake
+const certificateEndpointTimeout = 10 * time.Second
+
+// certificateExtensions are the file extensions which will be read if a directory is configured
+var certificateExtensions = []string{".pem", ".crt", ".cer", ".der"}
+
+// CheckCertificates gathers the expiry and chain validity of certificates in local files and of TLS endpoints
+type CheckCertificates struct {
+	files     []string
+	endpoints []string
+
+	// roots used to verify the chain, nil uses the system pool
+	roots *x509.CertPool
+	// endpointTimeout per endpoint, 0 uses certificateEndpointTimeout
+	endpointTimeout time.Duration
+}
+
+// Name will be used in the response as check name
+func (c *CheckCertificates) Name() string {
+	return "certificates"
+}
+
+type resultCertificate struct {
+	Source        string   `json:"source"` // File name or host:port
+	Subject       string   `json:"subject"`
+	Issuer        string   `json:"issuer"`
+	Serial        string   `json:"serial"`
+	SANs          []string `json:"sans"`
+	IsCA          bool     `json:"is_ca"`
+	NotBefore     int64    `json:"not_before"` // Unix timestamp
+	NotAfter      int64    `json:"not_after"`  // Unix timestamp
+	DaysRemaining int64    `json:"days_remaining"`
+	Expired       bool     `json:"expired"`
+	ChainValid    bool     `json:"chain_valid"`
+	ChainError    string   `json:"chain_error"`
+}
+
+type resultCertificateError struct {
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+type resultCertificates struct {
+	Certificates []*resultCertificate      `json:"certificates"`
+	Errors       []*resultCertificateError `json:"errors"`
+}
+
+// certificateSANs returns all subject alternative names of the certificate
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+// newResultCertificate verifies the certificate with all other certificates of the same source as intermediates
+func (c *CheckCertificates) newResultCertificate(source string, cert *x509.Certificate, chain []*x509.Certificate, now time.Time) *resultCertificate {
+	result := &resultCertificate{
+		Source:        source,
+		Subject:       cert.Subject.String(),
+		Issuer:        cert.Issuer.String(),
+		Serial:        cert.SerialNumber.Text(16),
+		SANs:          certificateSANs(cert),
+		IsCA:          cert.IsCA,
+		NotBefore:     cert.NotBefore.Unix(),
+		NotAfter:      cert.NotAfter.Unix(),
+		DaysRemaining: int64(cert.NotAfter.Sub(now) / (24 * time.Hour)),
+		Expired:       now.After(cert.NotAfter),
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, other := range chain {
+		if other != cert {
+			intermediates.AddCert(other)
+		}
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		result.ChainError = err.Error()
+	} else {
+		result.ChainValid = true
+	}
+	return result
+}
+
+// certificateFiles returns the file itself or all certificate files of a directory
+func certificateFiles(name string) ([]string, error) {
+	stat, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return []string{name}, nil
+	}
+
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		for _, certExt := range certificateExtensions {
+			if ext == certExt {
+				files = append(files, filepath.Join(name, entry.Name()))
+				break
+			}
+		}
+	}
+	return files, nil
+}
+
+// endpointCertificates connects to the endpoint and returns the certificates presented by the server
+// The timeout applies to the connection and the TLS handshake, so a stuck endpoint does not block the other checks
+func endpointCertificates(ctx context.Context, endpoint string, timeout time.Duration) ([]*x509.Certificate, error) {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	endpointCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			// The chain is verified later on to report the error
+			InsecureSkipVerify: true,
+			ServerName:         host,
+		},
+	}
+	conn, err := dialer.DialContext(endpointCtx, "tcp", endpoint)
+	if err != nil {
+		if ctx.Err() == nil && endpointCtx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timeout after %s while connecting to %s", timeout, endpoint)
+		}
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate presented by %s", endpoint)
+	}
+	return certs, nil
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckCertificates) Run(ctx context.Context) (interface{}, error) {
+	result := &resultCertificates{
+		Certificates: []*resultCertificate{},
+		Errors:       []*resultCertificateError{},
+	}
+	now := time.Now()
+	timeout := c.endpointTimeout
+	if timeout == 0 {
+		timeout = certificateEndpointTimeout
+	}
+
+	addError := func(source string, err error) {
+		result.Errors = append(result.Errors, &resultCertificateError{
+			Source: source,
+			Error:  err.Error(),
+		})
+	}
+
+	for _, name := range c.files {
+		files, err := certificateFiles(name)
+		if err != nil {
+			addError(name, err)
+			continue
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				addError(file, err)
+				continue
+			}
+			certs, err := utils.ParseCertificates(data)
+			if err != nil {
+				addError(file, err)
+				continue
+			}
+			for _, cert := range certs {
+				result.Certificates = append(result.Certificates, c.newResultCertificate(file, cert, certs, now))
+			}
+		}
+	}
+
+	for _, endpoint := range c.endpoints {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		certs, err := endpointCertificates(ctx, endpoint, timeout)
+		if err != nil {
+			addError(endpoint, err)
+			continue
+		}
+		for _, cert := range certs {
+			result.Certificates = append(result.Certificates, c.newResultCertificate(endpoint, cert, certs, now))
+		}
+	}
+
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckCertificates) Configure(config *config.Configuration) (bool, error) {
+	c.files = []string{}
+	for _, file := range config.CertificatesFiles {
+		if file = strings.TrimSpace(file); file != "" {
+			c.files = append(c.files, file)
+		}
+	}
+	c.endpoints = []string{}
+	for _, endpoint := range config.CertificatesEndpoints {
+		if endpoint = strings.TrimSpace(endpoint); endpoint == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			return false, fmt.Errorf("invalid certificate endpoint '%s', expected host:port: %s", endpoint, err)
+		}
+		c.endpoints = append(c.endpoints, endpoint)
+	}
+	return config.Certificates, nil
+}
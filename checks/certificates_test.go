@@ -0,0 +1,175 @@
+package checks
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+func TestChecksCheckCertificatesEndpoint(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	endpoint := strings.TrimPrefix(server.URL, "https://")
+	check := &CheckCertificates{}
+	ok, err := check.Configure(&config.Configuration{
+		Certificates:          true,
+		CertificatesEndpoints: []string{endpoint},
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultCertificates)
+	if !ok {
+		t.Fatal("False type")
+	}
+	if len(result.Errors) != 0 || len(result.Certificates) != 1 {
+		t.Fatal("expected one certificate: ", result.Errors)
+	}
+	cert := result.Certificates[0]
+	if cert.Source != endpoint || cert.Subject != "O=Acme Co" || cert.Expired || cert.DaysRemaining < 1 {
+		t.Fatal("unexpected certificate: ", cert)
+	}
+	if len(cert.SANs) != 4 || cert.SANs[0] != "example.com" || cert.SANs[2] != "127.0.0.1" {
+		t.Fatal("unexpected SANs: ", cert.SANs)
+	}
+	if cert.ChainValid || cert.ChainError == "" {
+		t.Fatal("self signed test certificate should not be trusted by the system pool")
+	}
+
+	check.roots = x509.NewCertPool()
+	check.roots.AddCert(server.Certificate())
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(*resultCertificates)
+	if !result.Certificates[0].ChainValid {
+		t.Fatal("expected valid chain: ", result.Certificates[0].ChainError)
+	}
+}
+
+func TestChecksCheckCertificatesFiles(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	der := server.Certificate().Raw
+
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"server.pem": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"server.der": der,
+		"README":     []byte("not a certificate"),
+		"broken.crt": []byte("not a certificate"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := &CheckCertificates{}
+	if _, err := check.Configure(&config.Configuration{
+		CertificatesFiles: []string{dir, filepath.Join(dir, "server.pem"), filepath.Join(dir, "missing.pem")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := cr.(*resultCertificates)
+	if len(result.Certificates) != 3 {
+		t.Fatal("expected 3 certificates: ", len(result.Certificates))
+	}
+	if result.Certificates[0].Source != filepath.Join(dir, "server.der") || result.Certificates[1].Source != filepath.Join(dir, "server.pem") {
+		t.Fatal("unexpected sources: ", result.Certificates[0].Source, result.Certificates[1].Source)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Source != filepath.Join(dir, "broken.crt") || result.Errors[1].Source != filepath.Join(dir, "missing.pem") {
+		t.Fatal("unexpected errors: ", result.Errors)
+	}
+}
+
+func TestChecksCheckCertificatesEndpointTimeout(t *testing.T) {
+	// accepts the connection but never answers the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	check := &CheckCertificates{
+		endpointTimeout: 200 * time.Millisecond,
+	}
+	if _, err := check.Configure(&config.Configuration{
+		Certificates:          true,
+		CertificatesEndpoints: []string{listener.Addr().String(), strings.TrimPrefix(server.URL, "https://")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cr, err := check.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := cr.(*resultCertificates)
+	if len(result.Errors) != 1 || result.Errors[0].Source != listener.Addr().String() || !strings.Contains(result.Errors[0].Error, "timeout") {
+		t.Fatal("expected timeout of the stuck endpoint: ", result.Errors)
+	}
+	if len(result.Certificates) != 1 {
+		t.Fatal("expected the certificate of the second endpoint: ", len(result.Certificates))
+	}
+}
+
+func TestChecksCheckCertificatesInvalidEndpoint(t *testing.T) {
+	check := &CheckCertificates{}
+	if _, err := check.Configure(&config.Configuration{
+		CertificatesEndpoints: []string{"localhost"},
+	}); err == nil {
+		t.Fatal("expected error for endpoint without port")
+	}
+}
+
+func TestChecksCheckCertificates(t *testing.T) {
+	check := &CheckCertificates{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
@@ -0,0 +1,54 @@
+package checks
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cgroupV2Root returns the mount point of the cgroup v2 (unified) hierarchy
+// Returns an empty string if the system does not use cgroup v2
+func cgroupV2Root(sysfsCgroup string) string {
+	// pure cgroup v2 systems
+	if _, err := os.Stat(filepath.Join(sysfsCgroup, "cgroup.controllers")); err == nil {
+		return sysfsCgroup
+	}
+	// hybrid systems mount the unified hierarchy in a sub directory
+	unified := filepath.Join(sysfsCgroup, "unified")
+	if _, err := os.Stat(filepath.Join(unified, "cgroup.controllers")); err == nil {
+		return unified
+	}
+	return ""
+}
+
+// cgroupsForPatterns returns all cgroups below root which match one of the given glob patterns (e.g. system.slice/*.service)
+// The returned names are relative to root
+func cgroupsForPatterns(root string, patterns []string) []string {
+	found := map[string]bool{}
+	for _, pattern := range patterns {
+		pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || !info.IsDir() {
+				continue
+			}
+			if rel, err := filepath.Rel(root, match); err == nil {
+				found[rel] = true
+			}
+		}
+	}
+
+	cgroups := make([]string, 0, len(found))
+	for cgroup := range found {
+		cgroups = append(cgroups, cgroup)
+	}
+	sort.Strings(cgroups)
+	return cgroups
+}
@@ -0,0 +1,218 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/safemaths"
+)
+
+// CheckCgroups gathers resource usage of systemd slices and units from the cgroup v2 hierarchy
+type CheckCgroups struct {
+	units []string
+
+	cgroupPath    string
+	lastTimestamp time.Time
+	lastResults   map[string]*resultCgroup
+}
+
+// Name will be used in the response as check name
+func (c *CheckCgroups) Name() string {
+	return "cgroups"
+}
+
+type resultCgroup struct {
+	Timestamp int64  `json:"timestamp"` // Timestamp of the last check evaluation
+	Name      string `json:"name"`      // e.g.: system.slice/nginx.service
+
+	CPUUsageUsec uint64  `json:"cpu_usage_usec"` // Total CPU time in microseconds (Counter)
+	CPUPercent   float64 `json:"cpu_percent"`    // CPU usage since the last check evaluation, 100% = one CPU core
+
+	MemoryCurrent uint64  `json:"memory_current"` // Current memory usage in bytes
+	MemoryMax     uint64  `json:"memory_max"`     // Memory limit in bytes (0 = unlimited)
+	MemoryPeak    uint64  `json:"memory_peak"`    // Peak memory usage in bytes (Linux 5.19 and newer)
+	MemoryPercent float64 `json:"memory_percent"` // Used memory of the memory limit as percentage (0 if unlimited)
+
+	IoReadBytes           uint64 `json:"io_read_bytes"`            // Number of bytes read (Counter)
+	IoWriteBytes          uint64 `json:"io_write_bytes"`           // Number of bytes written (Counter)
+	IoReadBytesPerSecond  uint64 `json:"io_read_bytes_per_second"` // Number of bytes read per second
+	IoWriteBytesPerSecond uint64 `json:"io_write_bytes_per_second"`
+
+	OomEvents     uint64 `json:"oom_events"`      // Number of times the memory limit was reached (Counter)
+	OomKillEvents uint64 `json:"oom_kill_events"` // Number of processes killed by the OOM killer (Counter)
+	OomKillDelta  uint64 `json:"oom_kill_delta"`  // Number of processes killed by the OOM killer since the last check evaluation
+
+	Pids uint64 `json:"pids"` // Number of processes and threads
+}
+
+// readFlatKeyedFile reads flat keyed files like cpu.stat, memory.events or /proc/vmstat
+func readFlatKeyedFile(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values, scanner.Err()
+}
+
+// readCgroupValue reads single value files like memory.current, "max" results in 0
+func readCgroupValue(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// readCgroupIoStat sums up the read and written bytes of all devices from io.stat
+// Format: 8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0
+func readCgroupIoStat(path string) (uint64, uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var rbytes, wbytes uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			value, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				rbytes += value
+			case "wbytes":
+				wbytes += value
+			}
+		}
+	}
+	return rbytes, wbytes, scanner.Err()
+}
+
+func (c *CheckCgroups) readCgroup(root, name string, now time.Time, interval float64) *resultCgroup {
+	dir := filepath.Join(root, name)
+	result := &resultCgroup{
+		Timestamp: now.Unix(),
+		Name:      name,
+	}
+
+	// missing files are not an error, because not all controllers are enabled for every cgroup
+	if cpuStat, err := readFlatKeyedFile(filepath.Join(dir, "cpu.stat")); err == nil {
+		result.CPUUsageUsec = cpuStat["usage_usec"]
+	}
+	if value, err := readCgroupValue(filepath.Join(dir, "memory.current")); err == nil {
+		result.MemoryCurrent = value
+	}
+	if value, err := readCgroupValue(filepath.Join(dir, "memory.max")); err == nil {
+		result.MemoryMax = value
+	}
+	if value, err := readCgroupValue(filepath.Join(dir, "memory.peak")); err == nil {
+		result.MemoryPeak = value
+	}
+	if result.MemoryMax > 0 {
+		result.MemoryPercent = safemaths.DivideFloat64(float64(result.MemoryCurrent), float64(result.MemoryMax)) * 100.0
+	}
+	if rbytes, wbytes, err := readCgroupIoStat(filepath.Join(dir, "io.stat")); err == nil {
+		result.IoReadBytes = rbytes
+		result.IoWriteBytes = wbytes
+	}
+	if events, err := readFlatKeyedFile(filepath.Join(dir, "memory.events")); err == nil {
+		result.OomEvents = events["oom"]
+		result.OomKillEvents = events["oom_kill"]
+	}
+	if value, err := readCgroupValue(filepath.Join(dir, "pids.current")); err == nil {
+		result.Pids = value
+	}
+
+	if last, ok := c.lastResults[name]; ok {
+		if interval > 0 {
+			cpuDelta := ResetDiffUint64(last.CPUUsageUsec, result.CPUUsageUsec)
+			result.CPUPercent = safemaths.DivideFloat64(float64(cpuDelta), interval*1000000.0) * 100.0
+			result.IoReadBytesPerSecond = uint64(safemaths.DivideFloat64(float64(ResetDiffUint64(last.IoReadBytes, result.IoReadBytes)), interval))
+			result.IoWriteBytesPerSecond = uint64(safemaths.DivideFloat64(float64(ResetDiffUint64(last.IoWriteBytes, result.IoWriteBytes)), interval))
+		}
+		result.OomKillDelta = ResetDiffUint64(last.OomKillEvents, result.OomKillEvents)
+	}
+
+	return result
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckCgroups) Run(ctx context.Context) (interface{}, error) {
+	cgroupPath := c.cgroupPath
+	if cgroupPath == "" {
+		cgroupPath = "/sys/fs/cgroup"
+	}
+
+	results := make(map[string]*resultCgroup)
+
+	root := cgroupV2Root(cgroupPath)
+	if root == "" {
+		// cgroup v1 only systems are not supported
+		return results, nil
+	}
+
+	units := c.units
+	if len(units) == 0 {
+		units = []string{"system.slice/*.service"}
+	}
+
+	now := time.Now()
+	interval := 0.0
+	if !c.lastTimestamp.IsZero() {
+		interval = now.Sub(c.lastTimestamp).Seconds()
+	}
+
+	for _, name := range cgroupsForPatterns(root, units) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		results[name] = c.readCgroup(root, name, now, interval)
+	}
+
+	c.lastTimestamp = now
+	c.lastResults = results
+	return results, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckCgroups) Configure(config *config.Configuration) (bool, error) {
+	c.units = config.CgroupsUnits
+	return config.Cgroups, nil
+}
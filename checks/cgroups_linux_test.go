@@ -0,0 +1,127 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChecksCheckCgroupsFixtures(t *testing.T) {
+	cgroupPath := t.TempDir()
+	writeFixtureFiles(t, cgroupPath, map[string]string{"cgroup.controllers": "cpu io memory pids\n"})
+
+	nginx := filepath.Join(cgroupPath, "system.slice", "nginx.service")
+	writeFixtureFiles(t, nginx, map[string]string{
+		"cpu.stat":       "usage_usec 5000000\nuser_usec 4000000\nsystem_usec 1000000\n",
+		"memory.current": "104857600\n",
+		"memory.max":     "209715200\n",
+		"memory.peak":    "157286400\n",
+		"memory.events":  "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\noom_group_kill 0\n",
+		"io.stat":        "8:0 rbytes=1000 wbytes=2000 rios=1 wios=2 dbytes=0 dios=0\n259:0 rbytes=3000 wbytes=4000 rios=3 wios=4 dbytes=0 dios=0\n",
+		"pids.current":   "12\n",
+	})
+	writeFixtureFiles(t, filepath.Join(cgroupPath, "system.slice", "cron.service"), map[string]string{
+		"memory.current": "1048576\n",
+		"memory.max":     "max\n",
+	})
+	writeFixtureFiles(t, filepath.Join(cgroupPath, "system.slice", "session.scope"), map[string]string{
+		"memory.current": "1048576\n",
+	})
+
+	check := &CheckCgroups{
+		cgroupPath: cgroupPath,
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, ok := cr.(map[string]*resultCgroup)
+	if !ok {
+		t.Fatal("False type")
+	}
+	if len(results) != 2 {
+		t.Fatal("expected the two services of the default pattern: ", len(results))
+	}
+
+	name := filepath.Join("system.slice", "nginx.service")
+	result := results[name]
+	if result.CPUUsageUsec != 5000000 || result.MemoryCurrent != 104857600 || result.MemoryMax != 209715200 || result.MemoryPeak != 157286400 {
+		t.Fatal("unexpected cpu or memory values: ", result)
+	}
+	if result.MemoryPercent != 50.0 {
+		t.Fatal("unexpected memory percentage: ", result.MemoryPercent)
+	}
+	if result.IoReadBytes != 4000 || result.IoWriteBytes != 6000 {
+		t.Fatal("unexpected io values: ", result.IoReadBytes, result.IoWriteBytes)
+	}
+	if result.OomEvents != 1 || result.OomKillEvents != 1 || result.OomKillDelta != 0 || result.Pids != 12 {
+		t.Fatal("unexpected oom or pid values: ", result)
+	}
+	if cron := results[filepath.Join("system.slice", "cron.service")]; cron.MemoryMax != 0 || cron.MemoryPercent != 0 {
+		t.Fatal("expected unlimited memory for cron.service: ", cron)
+	}
+
+	// 2 seconds of cpu time and one oom kill within 10 seconds
+	check.lastTimestamp = time.Now().Add(-10 * time.Second)
+	writeFixtureFiles(t, nginx, map[string]string{
+		"cpu.stat":      "usage_usec 7000000\n",
+		"memory.events": "oom 2\noom_kill 2\n",
+		"io.stat":       "8:0 rbytes=11000 wbytes=2000\n259:0 rbytes=3000 wbytes=4000\n",
+	})
+
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(map[string]*resultCgroup)[name]
+	if result.CPUPercent < 19.9 || result.CPUPercent > 20.1 {
+		t.Fatal("unexpected cpu percentage: ", result.CPUPercent)
+	}
+	if result.IoReadBytesPerSecond < 990 || result.IoReadBytesPerSecond > 1000 || result.IoWriteBytesPerSecond != 0 {
+		t.Fatal("unexpected io rates: ", result.IoReadBytesPerSecond, result.IoWriteBytesPerSecond)
+	}
+	if result.OomKillDelta != 1 {
+		t.Fatal("unexpected oom kill delta: ", result.OomKillDelta)
+	}
+
+	// restarting the unit recreates the cgroup and resets all counters
+	check.lastTimestamp = time.Now().Add(-10 * time.Second)
+	writeFixtureFiles(t, nginx, map[string]string{
+		"cpu.stat":      "usage_usec 1000000\n",
+		"memory.events": "oom 1\noom_kill 1\n",
+		"io.stat":       "8:0 rbytes=5000 wbytes=1000\n\n8:16\n",
+	})
+
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(map[string]*resultCgroup)[name]
+	if result.CPUPercent < 9.9 || result.CPUPercent > 10.1 {
+		t.Fatal("unexpected cpu percentage after restart: ", result.CPUPercent)
+	}
+	if result.IoReadBytes != 5000 || result.IoReadBytesPerSecond < 495 || result.IoReadBytesPerSecond > 500 || result.IoWriteBytesPerSecond < 95 || result.IoWriteBytesPerSecond > 100 {
+		t.Fatal("unexpected io rates after restart: ", result.IoReadBytesPerSecond, result.IoWriteBytesPerSecond)
+	}
+	if result.OomKillDelta != 1 {
+		t.Fatal("unexpected oom kill delta after restart: ", result.OomKillDelta)
+	}
+}
+
+func TestChecksCheckCgroups(t *testing.T) {
+	check := &CheckCgroups{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
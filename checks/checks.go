@@ -92,3 +92,14 @@ func WrapDiffInt64(last, curr int64) int64 {
 
 	return (math.MaxInt64 - last) + curr
 }
+
+// ResetDiffUint64 returns the difference of two counter values
+// A counter which is smaller than the last value has been reset (e.g. by recreating a cgroup),
+// so the current value is the delta since the reset
+func ResetDiffUint64(last, curr uint64) uint64 {
+	if last <= curr {
+		return curr - last
+	}
+
+	return curr
+}
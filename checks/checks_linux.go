@@ -4,9 +4,13 @@
 package checks
 
 func getPlatformChecks() []Check {
+	// CheckProcessGroups and CheckProcessTop reuse the processes gathered by CheckProcess
+	process := &CheckProcess{}
 	return []Check{
 		&CheckMem{},
-		&CheckProcess{},
+		process,
+		&CheckProcessGroups{process: process},
+		&CheckProcessTop{process: process},
 		&CheckAgent{},
 		&CheckSwap{},
 		&CheckUser{},
@@ -19,6 +23,21 @@ func getPlatformChecks() []Check {
 		&CheckSensor{},
 		&CheckDocker{},
 		&CheckSystemd{},
+		&CheckSystemdTimers{},
+		&CheckJournald{},
+		&CheckUpdates{},
+		&CheckPressure{},
+		&CheckCgroups{},
+		&CheckKernelEvents{},
+		&CheckMdraid{},
+		&CheckStoragePools{},
+		&CheckSmart{},
+		&CheckSockets{},
+		&CheckListenPorts{},
+		&CheckKernelLimits{},
 		&CheckNtp{},
+		&CheckCertificates{},
+		&CheckLogfile{},
+		&CheckFiles{},
 	}
 }
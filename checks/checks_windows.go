@@ -1,9 +1,13 @@
 package checks
 
 func getPlatformChecks() []Check {
+	// CheckProcessGroups and CheckProcessTop reuse the processes gathered by CheckProcess
+	process := &CheckProcess{}
 	return []Check{
 		&CheckMem{},
-		&CheckProcess{},
+		process,
+		&CheckProcessGroups{process: process},
+		&CheckProcessTop{process: process},
 		&CheckAgent{},
 		&CheckSwap{},
 		&CheckUser{},
@@ -16,5 +20,8 @@ func getPlatformChecks() []Check {
 		&CheckWinService{},
 		&CheckWindowsEventLog{},
 		&CheckNtp{},
+		&CheckCertificates{},
+		&CheckLogfile{},
+		&CheckFiles{},
 	}
 }
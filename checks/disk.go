@@ -1,11 +1,19 @@
 package checks
 
 import (
+	"path"
+	"strings"
+
 	"github.com/it-novum/openitcockpit-agent-go/config"
 )
 
 // CheckDisk gathers information about system disks
 type CheckDisk struct {
+	ignoreDevices      []string
+	includeFstypes     []string
+	excludeFstypes     []string
+	includeMountpoints []string
+	excludeMountpoints []string
 }
 
 // Name will be used in the response as check name
@@ -26,9 +34,55 @@ type resultDisk struct {
 		Free    uint64  `json:"free"`    // Free disk space in byte
 		Percent float64 `json:"percent"` // Used disk spaces as percent
 	} `json:"usage"`
+	Inodes struct {
+		Total   uint64  `json:"total"`   // Total number of inodes (macOS and Linux only)
+		Used    uint64  `json:"used"`    // Number of used inodes (macOS and Linux only)
+		Free    uint64  `json:"free"`    // Number of free inodes (macOS and Linux only)
+		Percent float64 `json:"percent"` // Used inodes as percent (macOS and Linux only)
+	} `json:"inodes"`
+}
+
+// matchesAnyPattern returns true if the value matches one of the given glob patterns (e.g. /run/*, nfs*)
+func matchesAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if pattern == value {
+			return true
+		}
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreDisk returns true if the disk is filtered by the ignore, include or exclude lists
+func (c *CheckDisk) ignoreDisk(device, fstype, mountpoint string) bool {
+	if matchesAnyPattern(c.ignoreDevices, device) {
+		return true
+	}
+	// the file system type is not available on Windows
+	if fstype != "" && len(c.includeFstypes) > 0 && !matchesAnyPattern(c.includeFstypes, fstype) {
+		return true
+	}
+	if matchesAnyPattern(c.excludeFstypes, fstype) {
+		return true
+	}
+	if len(c.includeMountpoints) > 0 && !matchesAnyPattern(c.includeMountpoints, mountpoint) {
+		return true
+	}
+	return matchesAnyPattern(c.excludeMountpoints, mountpoint)
 }
 
 // Configure the command or return false if the command was disabled
 func (c *CheckDisk) Configure(config *config.Configuration) (bool, error) {
+	c.ignoreDevices = config.DiskstatsIgnoreDevices
+	c.includeFstypes = config.DiskstatsIncludeFstypes
+	c.excludeFstypes = config.DiskstatsExcludeFstypes
+	c.includeMountpoints = config.DiskstatsIncludeMountpoints
+	c.excludeMountpoints = config.DiskstatsExcludeMountpoints
 	return config.Diskstats, nil
 }
@@ -32,3 +32,60 @@ func TestChecksCheckDisk(t *testing.T) {
 		}
 	}
 }
+
+func TestChecksCheckDiskIgnore(t *testing.T) {
+	check := &CheckDisk{
+		ignoreDevices:      []string{"tmpfs", "/dev/loop*"},
+		excludeFstypes:     []string{"nfs*"},
+		includeMountpoints: []string{"/", "/srv/*"},
+		excludeMountpoints: []string{"/srv/cache"},
+	}
+
+	tests := []struct {
+		device     string
+		fstype     string
+		mountpoint string
+		ignored    bool
+	}{
+		{"/dev/sda1", "ext4", "/", false},
+		{"/dev/sdb1", "xfs", "/srv/data", false},
+		{"tmpfs", "tmpfs", "/", true},
+		{"/dev/loop3", "squashfs", "/", true},
+		{"server:/export", "nfs4", "/srv/nfs", true},
+		{"/dev/sdc1", "ext4", "/srv/cache", true},
+		{"/dev/sdd1", "ext4", "/home", true},
+	}
+	for _, test := range tests {
+		if ignored := check.ignoreDisk(test.device, test.fstype, test.mountpoint); ignored != test.ignored {
+			t.Error("unexpected result for ", test.device, test.mountpoint, ": ", ignored)
+		}
+	}
+
+	check = &CheckDisk{
+		includeFstypes: []string{"ext4"},
+	}
+	if check.ignoreDisk("/dev/sda1", "ext4", "/") || !check.ignoreDisk("/dev/sdb1", "xfs", "/srv") {
+		t.Fatal("unexpected result for included file system types")
+	}
+}
+
+func TestChecksCheckDiskInodes(t *testing.T) {
+	check := &CheckDisk{
+		includeMountpoints: []string{"/"},
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range cr.([]*resultDisk) {
+		if result.Disk.Mountpoint != "/" {
+			t.Fatal("unexpected mountpoint: ", result.Disk.Mountpoint)
+		}
+		// some file systems like btrfs do not have a fixed number of inodes
+		if result.Inodes.Total > 0 && result.Inodes.Used+result.Inodes.Free != result.Inodes.Total {
+			t.Fatal("unexpected inode values: ", result.Inodes)
+		}
+	}
+}
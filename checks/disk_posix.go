@@ -10,31 +10,32 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var devToIgnore = map[string]bool{
-	"sysfs":       true,
-	"proc":        true,
-	"udev":        true,
-	"devpts":      true,
-	"devfs":       true,
-	"tmpfs":       true,
-	"securityfs":  true,
-	"cgroup":      true,
-	"cgroup2":     true,
-	"pstore":      true,
-	"debugfs":     true,
-	"hugetlbfs":   true,
-	"systemd-1":   true,
-	"mqueue":      true,
-	"none":        true,
-	"sunrpc":      true,
-	"nfsd":        true,
-	"nsfs":        true,
-	"fusectl":     true,
-	"configfs":    true,
-	"overlay":     true,
-	"shm":         true,
-	"tracefs":     true,
-	"binfmt_misc": true,
+// devToIgnore is used if no ignore list was configured
+var devToIgnore = []string{
+	"sysfs",
+	"proc",
+	"udev",
+	"devpts",
+	"devfs",
+	"tmpfs",
+	"securityfs",
+	"cgroup",
+	"cgroup2",
+	"pstore",
+	"debugfs",
+	"hugetlbfs",
+	"systemd-1",
+	"mqueue",
+	"none",
+	"sunrpc",
+	"nfsd",
+	"nsfs",
+	"fusectl",
+	"configfs",
+	"overlay",
+	"shm",
+	"tracefs",
+	"binfmt_misc",
 }
 
 // Run the actual check
@@ -42,6 +43,9 @@ var devToIgnore = map[string]bool{
 // ctx can be canceled and runs the timeout
 // CheckResult will be serialized after the return and should not change until the next call to Run
 func (c *CheckDisk) Run(ctx context.Context) (interface{}, error) {
+	if c.ignoreDevices == nil {
+		c.ignoreDevices = devToIgnore
+	}
 
 	disks, err := disk.PartitionsWithContext(ctx, true)
 	if err != nil {
@@ -50,7 +54,7 @@ func (c *CheckDisk) Run(ctx context.Context) (interface{}, error) {
 	diskResults := make([]*resultDisk, 0, len(disks))
 
 	for _, device := range disks {
-		if devToIgnore[device.Device] {
+		if c.ignoreDisk(device.Device, device.Fstype, device.Mountpoint) {
 			continue
 		}
 
@@ -77,6 +81,11 @@ func (c *CheckDisk) Run(ctx context.Context) (interface{}, error) {
 			result.Usage.Used = usage.Used
 			result.Usage.Free = usage.Free
 			result.Usage.Percent = usage.UsedPercent
+
+			result.Inodes.Total = usage.InodesTotal
+			result.Inodes.Used = usage.InodesUsed
+			result.Inodes.Free = usage.InodesFree
+			result.Inodes.Percent = usage.InodesUsedPercent
 		}
 
 		diskResults = append(diskResults, result)
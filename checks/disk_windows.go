@@ -66,7 +66,7 @@ func (c *CheckDisk) Run(_ context.Context) (interface{}, error) {
 		}
 
 		for _, disk := range dst {
-			if disk.Name != "_Total" {
+			if disk.Name != "_Total" && !c.ignoreDisk(disk.Name, "", disk.Name) {
 				//Do the math
 				totalDiskSpaceBytes := disk.PercentFreeSpace * 1024 * 1024
 				freeDiskSpaceBytes := disk.PercentFreeSpace_Base * 1024 * 1024
@@ -0,0 +1,166 @@
+package checks
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+// CheckFiles gathers existence, size and age of files and directories e.g. backups and spool directories
+type CheckFiles struct {
+	files []*config.FileStat
+}
+
+// Name will be used in the response as check name
+func (c *CheckFiles) Name() string {
+	return "files"
+}
+
+type resultFile struct {
+	File struct {
+		Name    string `json:"name"`    // Name of the section in the filestats.ini
+		Path    string `json:"path"`    // e.g.: /var/backups/db.tar.gz
+		Pattern string `json:"pattern"` // Configured path or glob e.g.: /var/backups/*.tar.gz
+		Exists  bool   `json:"exists"`  // false if the path does not exist or the glob does not match any file
+		IsDir   bool   `json:"is_dir"`
+	} `json:"file"`
+	Usage struct {
+		Size  uint64 `json:"size"`  // Size in byte (directories: sum of all files)
+		Mtime int64  `json:"mtime"` // Last modification as unix timestamp
+		Age   int64  `json:"age"`   // Seconds since the last modification
+	} `json:"usage"`
+	Directory struct {
+		Files       uint64 `json:"files"`       // Number of files
+		Directories uint64 `json:"directories"` // Number of sub directories
+		NewestFile  string `json:"newest_file"` // e.g.: /var/spool/postfix/deferred/A/A1B2C3
+		NewestAge   int64  `json:"newest_age"`  // Seconds since the last modification of the newest file
+		OldestFile  string `json:"oldest_file"`
+		OldestAge   int64  `json:"oldest_age"` // Seconds since the last modification of the oldest file
+	} `json:"directory"`
+	Error string `json:"error"`
+}
+
+func ageSeconds(now, mtime time.Time) int64 {
+	age := int64(now.Sub(mtime) / time.Second)
+	if age < 0 {
+		// mtime in the future
+		return 0
+	}
+	return age
+}
+
+// walkDirectory sums up all files of the directory up to the depth limit of the entry
+func (c *CheckFiles) walkDirectory(ctx context.Context, root string, file *config.FileStat, now time.Time, result *resultFile) error {
+	var newest, oldest time.Time
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == root {
+			return err
+		}
+		if err != nil {
+			// e.g.: permission denied of a sub directory
+			return nil
+		}
+
+		if d.IsDir() {
+			// entries of the directory itself have the depth 1
+			rel, _ := filepath.Rel(root, path)
+			depth := strings.Count(rel, string(filepath.Separator)) + 1
+			result.Directory.Directories++
+			if !file.Recursive || (file.MaxDepth > 0 && int64(depth) >= file.MaxDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		result.Directory.Files++
+		result.Usage.Size += uint64(info.Size())
+		if newest.IsZero() || info.ModTime().After(newest) {
+			newest = info.ModTime()
+			result.Directory.NewestFile = path
+		}
+		if oldest.IsZero() || info.ModTime().Before(oldest) {
+			oldest = info.ModTime()
+			result.Directory.OldestFile = path
+		}
+		return nil
+	})
+	if !newest.IsZero() {
+		result.Directory.NewestAge = ageSeconds(now, newest)
+		result.Directory.OldestAge = ageSeconds(now, oldest)
+	}
+	return err
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckFiles) Run(ctx context.Context) (interface{}, error) {
+	results := []*resultFile{}
+	now := time.Now()
+
+	for _, file := range c.files {
+		pattern := file.Path
+		paths, err := filepath.Glob(pattern)
+		if err != nil || len(paths) == 0 {
+			result := &resultFile{}
+			result.File.Name = file.Name
+			result.File.Path = pattern
+			result.File.Pattern = pattern
+			if err != nil {
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+			continue
+		}
+
+		for _, path := range paths {
+			result := &resultFile{}
+			result.File.Name = file.Name
+			result.File.Path = path
+			result.File.Pattern = pattern
+			results = append(results, result)
+
+			info, err := os.Stat(path)
+			if err != nil {
+				result.Error = err.Error()
+				continue
+			}
+			result.File.Exists = true
+			result.File.IsDir = info.IsDir()
+			result.Usage.Mtime = info.ModTime().Unix()
+			result.Usage.Age = ageSeconds(now, info.ModTime())
+			if !info.IsDir() {
+				result.Usage.Size = uint64(info.Size())
+				continue
+			}
+
+			if err := c.walkDirectory(ctx, path, file, now, result); err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, ctxErr
+				}
+				result.Error = err.Error()
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckFiles) Configure(config *config.Configuration) (bool, error) {
+	c.files = config.FileStatConfiguration
+	return config.Files && len(c.files) > 0, nil
+}
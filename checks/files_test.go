@@ -0,0 +1,118 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+func writeFileWithAge(t *testing.T, path string, size int, age time.Duration) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runFilesCheck(t *testing.T, cfg *config.Configuration) []*resultFile {
+	check := &CheckFiles{}
+	ok, err := check.Configure(cfg)
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, ok := cr.([]*resultFile)
+	if !ok {
+		t.Fatal("False type")
+	}
+	return results
+}
+
+func TestChecksCheckFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFileWithAge(t, filepath.Join(dir, "backup", "db.tar.gz"), 100, 2*time.Hour)
+	writeFileWithAge(t, filepath.Join(dir, "spool", "new"), 10, time.Minute)
+	writeFileWithAge(t, filepath.Join(dir, "spool", "A", "old"), 20, 3*time.Hour)
+	writeFileWithAge(t, filepath.Join(dir, "spool", "A", "B", "oldest"), 30, 5*time.Hour)
+
+	results := runFilesCheck(t, &config.Configuration{
+		Files: true,
+		FileStatConfiguration: []*config.FileStat{
+			{Name: "backup", Enabled: true, Path: filepath.Join(dir, "backup", "*.tar.gz")},
+			{Name: "missing", Enabled: true, Path: filepath.Join(dir, "missing", "*.tar.gz")},
+			{Name: "spool", Enabled: true, Path: filepath.Join(dir, "spool")},
+		},
+	})
+	if len(results) != 3 {
+		t.Fatal("expected 3 results, got ", len(results))
+	}
+
+	backup := results[0]
+	if backup.File.Name != "backup" || !backup.File.Exists || backup.File.IsDir || backup.Usage.Size != 100 || backup.Usage.Age < 7100 || backup.Usage.Age > 7300 {
+		t.Fatal("unexpected file result: ", backup)
+	}
+
+	missing := results[1]
+	if missing.File.Exists || missing.Error != "" {
+		t.Fatal("expected not existing result for glob without match: ", missing)
+	}
+
+	spool := results[2]
+	if !spool.File.IsDir || spool.Directory.Files != 1 || spool.Directory.Directories != 1 || spool.Usage.Size != 10 {
+		t.Fatal("expected only direct entries without recursive: ", spool.Directory, spool.Usage)
+	}
+
+	js, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
+
+func TestChecksCheckFilesRecursive(t *testing.T) {
+	dir := t.TempDir()
+	writeFileWithAge(t, filepath.Join(dir, "new"), 10, time.Minute)
+	writeFileWithAge(t, filepath.Join(dir, "A", "old"), 20, 3*time.Hour)
+	writeFileWithAge(t, filepath.Join(dir, "A", "B", "oldest"), 30, 5*time.Hour)
+
+	// the same directory with different depth limits per entry
+	results := runFilesCheck(t, &config.Configuration{
+		Files: true,
+		FileStatConfiguration: []*config.FileStat{
+			{Name: "unlimited", Enabled: true, Path: dir, Recursive: true},
+			{Name: "limited", Enabled: true, Path: dir, Recursive: true, MaxDepth: 2},
+		},
+	})
+	if len(results) != 2 {
+		t.Fatal("expected 2 results, got ", len(results))
+	}
+	result := results[0]
+	if result.Directory.Files != 3 || result.Directory.Directories != 2 || result.Usage.Size != 60 {
+		t.Fatal("unexpected recursive counts: ", result.Directory, result.Usage)
+	}
+	if result.Directory.NewestFile != filepath.Join(dir, "new") || result.Directory.OldestFile != filepath.Join(dir, "A", "B", "oldest") {
+		t.Fatal("unexpected newest or oldest file: ", result.Directory)
+	}
+	if result.Directory.OldestAge < 5*3600-100 || result.Directory.NewestAge > 200 {
+		t.Fatal("unexpected newest or oldest age: ", result.Directory)
+	}
+
+	result = results[1]
+	if result.Directory.Files != 2 || result.Directory.Directories != 2 || result.Usage.Size != 30 {
+		t.Fatal("expected depth limit to skip the files of A/B: ", result.Directory, result.Usage)
+	}
+}
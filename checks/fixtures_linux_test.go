@@ -0,0 +1,21 @@
+package checks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFixtureFiles creates the files with the given content below dir
+// The file names are slash separated paths relative to dir
+func writeFixtureFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// journaldPriorityNames are the syslog priorities used by journalctl -p
+var journaldPriorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// CheckJournald counts new entries of the systemd journal matching the units, priority and regex
+type CheckJournald struct {
+	units       []string
+	priority    int64
+	regex       *regexp.Regexp
+	maxMessages int
+	stateFile   string
+
+	cursor      string
+	initialized bool
+}
+
+// Name will be used in the response as check name
+func (c *CheckJournald) Name() string {
+	return "journald"
+}
+
+type resultJournaldEntry struct {
+	Timestamp  int64  `json:"timestamp"`  // Unix timestamp of the journal entry
+	Unit       string `json:"unit"`       // e.g.: nginx.service
+	Identifier string `json:"identifier"` // Syslog identifier e.g.: nginx
+	Priority   int64  `json:"priority"`   // syslog priority (0 = emerg ... 7 = debug)
+	Message    string `json:"message"`
+}
+
+type resultJournald struct {
+	Entries    uint64                 `json:"entries"`    // Entries of the configured units and priority since the last check evaluation
+	Matches    uint64                 `json:"matches"`    // Entries also matching the regex since the last check evaluation
+	Priorities map[string]uint64      `json:"priorities"` // Matches per priority e.g.: err, warning
+	Units      map[string]uint64      `json:"units"`      // Matches per unit
+	Messages   []*resultJournaldEntry `json:"messages"`   // Last matching entries
+	Error      string                 `json:"error"`      // e.g.: journalctl not found
+}
+
+// journaldEntry is a line of journalctl -o json
+type journaldEntry struct {
+	Cursor     string          `json:"__CURSOR"`
+	Realtime   string          `json:"__REALTIME_TIMESTAMP"` // usec since the epoch
+	Message    json.RawMessage `json:"MESSAGE"`              // string or byte array for binary messages
+	Priority   string          `json:"PRIORITY"`
+	Unit       string          `json:"_SYSTEMD_UNIT"`
+	Identifier string          `json:"SYSLOG_IDENTIFIER"`
+}
+
+func (e *journaldEntry) message() string {
+	var message string
+	if err := json.Unmarshal(e.Message, &message); err == nil {
+		return message
+	}
+	// binary messages are encoded as array of numbers
+	var numbers []int
+	if err := json.Unmarshal(e.Message, &numbers); err == nil {
+		data := make([]byte, len(numbers))
+		for i, n := range numbers {
+			data[i] = byte(n)
+		}
+		return string(data)
+	}
+	return ""
+}
+
+// journalctlCommand returns the journalctl command to read all entries after the cursor
+// Without a cursor only the last entry will be read to get the current cursor
+func (c *CheckJournald) journalctlCommand(cursor string) string {
+	args := []string{"journalctl", "--no-pager", "--quiet", "--output=json"}
+	if cursor == "" {
+		return strings.Join(append(args, "--lines=1"), " ")
+	}
+	args = append(args, fmt.Sprintf("--after-cursor='%s'", cursor), fmt.Sprintf("--priority=%d", c.priority))
+	for _, unit := range c.units {
+		args = append(args, fmt.Sprintf("--unit='%s'", unit))
+	}
+	return strings.Join(args, " ")
+}
+
+// processJournalEntries counts all matching entries and returns the cursor of the last entry
+func (c *CheckJournald) processJournalEntries(output string, result *resultJournald) string {
+	cursor := ""
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			// e.g.: hints about missing permissions
+			continue
+		}
+		entry := &journaldEntry{}
+		if err := json.Unmarshal(line, entry); err != nil {
+			log.Debugln("Journald: could not parse entry: ", err)
+			continue
+		}
+		if entry.Cursor != "" {
+			cursor = entry.Cursor
+		}
+		result.Entries++
+
+		message := entry.message()
+		if c.regex != nil && !c.regex.MatchString(message) {
+			continue
+		}
+		result.Matches++
+
+		priority, err := strconv.ParseInt(entry.Priority, 10, 64)
+		if err != nil || priority < 0 || priority >= int64(len(journaldPriorityNames)) {
+			priority = 6
+		}
+		result.Priorities[journaldPriorityNames[priority]]++
+		unit := entry.Unit
+		if unit == "" {
+			unit = entry.Identifier
+		}
+		result.Units[unit]++
+
+		if c.maxMessages > 0 {
+			timestamp := int64(0)
+			if usec, err := strconv.ParseInt(entry.Realtime, 10, 64); err == nil {
+				timestamp = usec / 1000000
+			}
+			if len(message) > maxLogfileLineLength {
+				message = message[:maxLogfileLineLength]
+			}
+			result.Messages = append(result.Messages, &resultJournaldEntry{
+				Timestamp:  timestamp,
+				Unit:       entry.Unit,
+				Identifier: entry.Identifier,
+				Priority:   priority,
+				Message:    message,
+			})
+			if len(result.Messages) > c.maxMessages {
+				result.Messages = result.Messages[len(result.Messages)-c.maxMessages:]
+			}
+		}
+	}
+	return cursor
+}
+
+func (c *CheckJournald) loadCursor() {
+	c.initialized = true
+	if c.stateFile == "" || utils.FileNotExists(c.stateFile) {
+		return
+	}
+	data, err := os.ReadFile(c.stateFile)
+	if err != nil {
+		log.Errorln("Journald: could not read cursor file: ", err)
+		return
+	}
+	c.cursor = strings.TrimSpace(string(data))
+}
+
+func (c *CheckJournald) saveCursor() error {
+	if c.stateFile == "" {
+		return nil
+	}
+
+	// write to a temporary file first, so we never leave a partially written cursor file behind
+	tmpPath := c.stateFile + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(c.cursor+"\n"), 0600); err != nil {
+		return fmt.Errorf("could not write cursor file: %s", err)
+	}
+	if err := os.Rename(tmpPath, c.stateFile); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("could not write cursor file: %s", err)
+	}
+	return nil
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckJournald) Run(ctx context.Context) (interface{}, error) {
+	result := &resultJournald{
+		Priorities: map[string]uint64{},
+		Units:      map[string]uint64{},
+		Messages:   []*resultJournaldEntry{},
+	}
+	if !c.initialized {
+		c.loadCursor()
+	}
+
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		result.Error = "journalctl not found"
+		return result, nil
+	}
+
+	first := c.cursor == ""
+	command := c.journalctlCommand(c.cursor)
+	commandResult, err := utils.RunCommand(ctx, utils.CommandArgs{
+		Command: command,
+		Timeout: 20 * time.Second,
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err != nil || commandResult.RC > 0 {
+		result.Error = fmt.Sprintf("Error while executing '%s': %s", command, strings.TrimSpace(commandResult.Stdout))
+		// the cursor may be invalid after the journal was rotated or vacuumed, start again at the end of the journal
+		c.cursor = ""
+		return result, nil
+	}
+
+	if first {
+		// do not report old entries of the journal on the first run
+		c.cursor = c.processJournalEntries(commandResult.Stdout, &resultJournald{
+			Priorities: map[string]uint64{},
+			Units:      map[string]uint64{},
+		})
+	} else if cursor := c.processJournalEntries(commandResult.Stdout, result); cursor != "" {
+		c.cursor = cursor
+	}
+
+	if c.cursor != "" && (first || result.Entries > 0) {
+		if err := c.saveCursor(); err != nil {
+			log.Errorln("Journald: ", err)
+		}
+	}
+
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckJournald) Configure(config *config.Configuration) (bool, error) {
+	c.units = []string{}
+	for _, unit := range config.JournaldUnits {
+		unit = strings.TrimSpace(unit)
+		if unit == "" {
+			continue
+		}
+		if strings.ContainsAny(unit, "'\" ") {
+			return false, fmt.Errorf("invalid journald unit: %s", unit)
+		}
+		c.units = append(c.units, unit)
+	}
+	if config.JournaldPriority < 0 || config.JournaldPriority >= int64(len(journaldPriorityNames)) {
+		return false, fmt.Errorf("invalid journald priority %d (0 = emerg ... 7 = debug)", config.JournaldPriority)
+	}
+	c.priority = config.JournaldPriority
+	c.regex = nil
+	if config.JournaldRegex != "" {
+		regex, err := regexp.Compile(config.JournaldRegex)
+		if err != nil {
+			return false, err
+		}
+		c.regex = regex
+	}
+	c.maxMessages = int(config.JournaldMessages)
+	c.stateFile = config.JournaldStateFile
+	return config.Journald, nil
+}
@@ -0,0 +1,133 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+var journaldEntriesFixture = `Hint: You are currently not seeing messages from other users and the system.
+{"__CURSOR":"s=abc;i=2","__REALTIME_TIMESTAMP":"1700000001000000","MESSAGE":"Started nginx","PRIORITY":"6","_SYSTEMD_UNIT":"nginx.service","SYSLOG_IDENTIFIER":"systemd"}
+{"__CURSOR":"s=abc;i=3","__REALTIME_TIMESTAMP":"1700000002000000","MESSAGE":"connect() failed (111: Connection refused)","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service","SYSLOG_IDENTIFIER":"nginx"}
+{"__CURSOR":"s=abc;i=4","__REALTIME_TIMESTAMP":"1700000003000000","MESSAGE":[98,97,99,107,117,112,32,102,97,105,108,101,100],"PRIORITY":"2","SYSLOG_IDENTIFIER":"backup"}
+{"__CURSOR":"s=abc;i=5","__REALTIME_TIMESTAMP":"1700000004000000","MESSAGE":"upstream timed out, request failed","PRIORITY":"4","_SYSTEMD_UNIT":"nginx.service","SYSLOG_IDENTIFIER":"nginx"}
+`
+
+func newJournaldCheck(t *testing.T, stateFile string) *CheckJournald {
+	check := &CheckJournald{}
+	ok, err := check.Configure(&config.Configuration{
+		Journald:          true,
+		JournaldUnits:     []string{"nginx.service", " backup.service "},
+		JournaldPriority:  4,
+		JournaldRegex:     "failed",
+		JournaldMessages:  2,
+		JournaldStateFile: stateFile,
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+	return check
+}
+
+func TestJournalctlCommand(t *testing.T) {
+	check := newJournaldCheck(t, "")
+	if command := check.journalctlCommand(""); command != "journalctl --no-pager --quiet --output=json --lines=1" {
+		t.Error("unexpected command without cursor: ", command)
+	}
+	command := check.journalctlCommand("s=abc;i=1")
+	if command != "journalctl --no-pager --quiet --output=json --after-cursor='s=abc;i=1' --priority=4 --unit='nginx.service' --unit='backup.service'" {
+		t.Error("unexpected command with cursor: ", command)
+	}
+
+	if _, err := check.Configure(&config.Configuration{JournaldUnits: []string{"nginx.service' --since=yesterday"}}); err == nil {
+		t.Error("expected error for invalid unit")
+	}
+	if _, err := check.Configure(&config.Configuration{JournaldPriority: 8}); err == nil {
+		t.Error("expected error for invalid priority")
+	}
+}
+
+func TestProcessJournalEntries(t *testing.T) {
+	check := newJournaldCheck(t, "")
+	result := &resultJournald{
+		Priorities: map[string]uint64{},
+		Units:      map[string]uint64{},
+	}
+	cursor := check.processJournalEntries(journaldEntriesFixture, result)
+	if cursor != "s=abc;i=5" {
+		t.Error("unexpected cursor: ", cursor)
+	}
+	if result.Entries != 4 || result.Matches != 3 {
+		t.Error("unexpected counts: ", result.Entries, result.Matches)
+	}
+	if result.Priorities["err"] != 1 || result.Priorities["crit"] != 1 || result.Priorities["warning"] != 1 {
+		t.Error("unexpected priorities: ", result.Priorities)
+	}
+	if result.Units["nginx.service"] != 2 || result.Units["backup"] != 1 {
+		t.Error("unexpected units: ", result.Units)
+	}
+	if len(result.Messages) != 2 || result.Messages[0].Message != "backup failed" || result.Messages[1].Timestamp != 1700000004 {
+		t.Error("unexpected last messages: ", result.Messages)
+	}
+}
+
+func TestChecksCheckJournald(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "entries.json"), []byte(journaldEntriesFixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+echo "$*" > "$(dirname "$0")/args"
+case "$*" in
+	*--after-cursor*) cat "$(dirname "$0")/entries.json" ;;
+	*) echo '{"__CURSOR":"s=abc;i=1","__REALTIME_TIMESTAMP":"1700000000000000","MESSAGE":"failed before the first run","PRIORITY":"3"}' ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "journalctl"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	stateFile := filepath.Join(dir, "journald_cursor")
+	check := newJournaldCheck(t, stateFile)
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := cr.(*resultJournald)
+	if result.Error != "" || result.Matches != 0 {
+		t.Fatal("expected old entries to be skipped on the first run: ", result)
+	}
+	if cursor, _ := os.ReadFile(stateFile); strings.TrimSpace(string(cursor)) != "s=abc;i=1" {
+		t.Fatal("unexpected cursor file: ", string(cursor))
+	}
+
+	// a new check instance (agent restart) continues at the stored cursor
+	check = newJournaldCheck(t, stateFile)
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(*resultJournald)
+	if result.Error != "" || result.Entries != 4 || result.Matches != 3 {
+		t.Fatal("unexpected result: ", result)
+	}
+	if args, _ := os.ReadFile(filepath.Join(dir, "args")); !strings.Contains(string(args), "--after-cursor=s=abc;i=1") {
+		t.Fatal("expected journalctl to be called with the stored cursor: ", string(args))
+	}
+	if cursor, _ := os.ReadFile(stateFile); strings.TrimSpace(string(cursor)) != "s=abc;i=5" {
+		t.Fatal("unexpected cursor file: ", string(cursor))
+	}
+
+	js, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
@@ -0,0 +1,273 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"golang.org/x/sys/unix"
+)
+
+// CheckKernelEvents gathers OOM killer events and important kernel messages
+type CheckKernelEvents struct {
+	cgroups     []string
+	kmsg        bool
+	maxMessages int
+
+	procPath       string
+	cgroupPath     string
+	kmsgPath       string
+	initialized    bool
+	lastOomKill    uint64
+	lastCgroupOoms map[string]uint64
+	lastSeq        uint64
+	messages       []*resultKernelMessage
+}
+
+// Name will be used in the response as check name
+func (c *CheckKernelEvents) Name() string {
+	return "kernel_events"
+}
+
+// Types of kernel messages
+const (
+	kernelMessageOom             = "oom"
+	kernelMessageHungTask        = "hung_task"
+	kernelMessageFilesystemError = "filesystem_error"
+)
+
+var kernelMessagePatterns = []struct {
+	Type    string
+	Pattern *regexp.Regexp
+}{
+	{kernelMessageOom, regexp.MustCompile(`(?i)(out of memory|invoked oom-killer|oom-kill:|killed process \d+)`)},
+	{kernelMessageHungTask, regexp.MustCompile(`(?i)(blocked for more than \d+ seconds|hung_task)`)},
+	{kernelMessageFilesystemError, regexp.MustCompile(`(?i)(EXT[234]-fs (error|warning)|XFS \(.*\): (corruption|metadata I/O error|.*shut ?down)|BTRFS (error|critical)|remounting filesystem read-only|I/O error)`)},
+}
+
+type resultKernelMessage struct {
+	Timestamp int64  `json:"timestamp"` // Unix timestamp of the kernel message
+	Type      string `json:"type"`      // oom, hung_task or filesystem_error
+	Priority  int    `json:"priority"`  // syslog priority (0 = emerg ... 7 = debug)
+	Message   string `json:"message"`
+}
+
+type resultKernelEventsCgroup struct {
+	OomKillEvents uint64 `json:"oom_kill_events"` // Number of processes killed by the OOM killer (Counter)
+	OomKillDelta  uint64 `json:"oom_kill_delta"`  // Number of processes killed by the OOM killer since the last check evaluation
+}
+
+type resultKernelEvents struct {
+	Timestamp    int64                                `json:"timestamp"`      // Timestamp of the last check evaluation
+	OomKill      uint64                               `json:"oom_kill"`       // Number of processes killed by the OOM killer since boot (Counter)
+	OomKillDelta uint64                               `json:"oom_kill_delta"` // Number of processes killed by the OOM killer since the last check evaluation
+	Cgroups      map[string]*resultKernelEventsCgroup `json:"cgroups"`        // OOM kills per configured cgroup
+
+	KmsgEnabled             bool                   `json:"kmsg_enabled"`
+	KmsgError               string                 `json:"kmsg_error"`                // e.g.: permission denied if kernel.dmesg_restrict = 1
+	OomMessages             uint64                 `json:"oom_messages"`              // Number of OOM messages since the last check evaluation
+	HungTaskMessages        uint64                 `json:"hung_task_messages"`        // Number of hung task messages since the last check evaluation
+	FilesystemErrorMessages uint64                 `json:"filesystem_error_messages"` // Number of filesystem error messages since the last check evaluation
+	Messages                []*resultKernelMessage `json:"messages"`                  // Last matching kernel messages
+}
+
+// parseKmsgRecord parses a record from /dev/kmsg
+// Format: <priority and facility>,<sequence>,<timestamp in microseconds since boot>,<flags>;<message>
+// followed by optional continuation lines starting with a space
+func parseKmsgRecord(record string) (priority int, seq uint64, usec uint64, message string, err error) {
+	header, rest, found := strings.Cut(record, ";")
+	if !found {
+		return 0, 0, 0, "", fmt.Errorf("invalid kmsg record")
+	}
+	fields := strings.Split(header, ",")
+	if len(fields) < 3 {
+		return 0, 0, 0, "", fmt.Errorf("invalid kmsg record header")
+	}
+	prio, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+	seq, err = strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+	usec, err = strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+
+	// remove continuation lines with the device information
+	message, _, _ = strings.Cut(rest, "\n")
+	return prio & 7, seq, usec, message, nil
+}
+
+// processKmsgRecords counts and stores all matching kernel messages newer than the last check evaluation
+func (c *CheckKernelEvents) processKmsgRecords(records []string, bootTime time.Time, result *resultKernelEvents) {
+	maxSeq := c.lastSeq
+	for _, record := range records {
+		priority, seq, usec, message, err := parseKmsgRecord(record)
+		if err != nil {
+			continue
+		}
+		if seq > maxSeq {
+			maxSeq = seq
+		}
+		// on the first run we only remember the position in the kernel ring buffer
+		if !c.initialized || seq <= c.lastSeq {
+			continue
+		}
+
+		for _, p := range kernelMessagePatterns {
+			if !p.Pattern.MatchString(message) {
+				continue
+			}
+			switch p.Type {
+			case kernelMessageOom:
+				result.OomMessages++
+			case kernelMessageHungTask:
+				result.HungTaskMessages++
+			case kernelMessageFilesystemError:
+				result.FilesystemErrorMessages++
+			}
+			c.messages = append(c.messages, &resultKernelMessage{
+				Timestamp: bootTime.Add(time.Duration(usec) * time.Microsecond).Unix(),
+				Type:      p.Type,
+				Priority:  priority,
+				Message:   message,
+			})
+			break
+		}
+	}
+	c.lastSeq = maxSeq
+
+	if len(c.messages) > c.maxMessages {
+		c.messages = c.messages[len(c.messages)-c.maxMessages:]
+	}
+}
+
+// readKmsg reads all records currently available in the kernel ring buffer
+// Every read on /dev/kmsg returns exactly one record
+func readKmsg(path string) ([]string, error) {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	records := []string{}
+	buf := make([]byte, 8192)
+	for {
+		n, err := unix.Read(fd, buf)
+		if err != nil {
+			if errors.Is(err, unix.EAGAIN) {
+				// no more records
+				return records, nil
+			}
+			if errors.Is(err, unix.EPIPE) {
+				// records got overwritten while reading, continue with the next one
+				continue
+			}
+			return records, err
+		}
+		if n <= 0 {
+			return records, nil
+		}
+		records = append(records, string(buf[:n]))
+	}
+}
+
+func bootTime() time.Time {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return time.Now()
+	}
+	return time.Now().Add(-time.Duration(ts.Nano()))
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckKernelEvents) Run(ctx context.Context) (interface{}, error) {
+	procPath := c.procPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+	cgroupPath := c.cgroupPath
+	if cgroupPath == "" {
+		cgroupPath = "/sys/fs/cgroup"
+	}
+	kmsgPath := c.kmsgPath
+	if kmsgPath == "" {
+		kmsgPath = "/dev/kmsg"
+	}
+	if c.maxMessages <= 0 {
+		c.maxMessages = 10
+	}
+
+	result := &resultKernelEvents{
+		Timestamp:   time.Now().Unix(),
+		Cgroups:     map[string]*resultKernelEventsCgroup{},
+		KmsgEnabled: c.kmsg,
+	}
+
+	vmstat, err := readFlatKeyedFile(filepath.Join(procPath, "vmstat"))
+	if err != nil {
+		return nil, err
+	}
+	// oom_kill is available since Linux 4.13
+	result.OomKill = vmstat["oom_kill"]
+	if c.initialized {
+		result.OomKillDelta = WrapDiffUint64(c.lastOomKill, result.OomKill)
+	}
+	c.lastOomKill = result.OomKill
+
+	cgroupOoms := map[string]uint64{}
+	if root := cgroupV2Root(cgroupPath); root != "" && len(c.cgroups) > 0 {
+		for _, cgroup := range cgroupsForPatterns(root, c.cgroups) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			events, err := readFlatKeyedFile(filepath.Join(root, cgroup, "memory.events"))
+			if err != nil {
+				continue
+			}
+			res := &resultKernelEventsCgroup{
+				OomKillEvents: events["oom_kill"],
+			}
+			if last, ok := c.lastCgroupOoms[cgroup]; ok {
+				res.OomKillDelta = ResetDiffUint64(last, res.OomKillEvents)
+			}
+			cgroupOoms[cgroup] = res.OomKillEvents
+			result.Cgroups[cgroup] = res
+		}
+	}
+	c.lastCgroupOoms = cgroupOoms
+
+	if c.kmsg {
+		records, err := readKmsg(kmsgPath)
+		if err != nil {
+			result.KmsgError = err.Error()
+		}
+		c.processKmsgRecords(records, bootTime(), result)
+	}
+
+	c.initialized = true
+	result.Messages = make([]*resultKernelMessage, len(c.messages))
+	copy(result.Messages, c.messages)
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckKernelEvents) Configure(config *config.Configuration) (bool, error) {
+	c.cgroups = config.KernelEventsCgroups
+	c.kmsg = config.KernelEventsKmsg
+	c.maxMessages = int(config.KernelEventsMessages)
+	return config.KernelEvents, nil
+}
@@ -0,0 +1,146 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseKmsgRecord(t *testing.T) {
+	priority, seq, usec, message, err := parseKmsgRecord("3,1234,5000000,-;Out of memory: Killed process 4711 (java) total-vm:1234kB\n SUBSYSTEM=memory\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priority != 3 || seq != 1234 || usec != 5000000 {
+		t.Fatal("unexpected header values: ", priority, seq, usec)
+	}
+	if message != "Out of memory: Killed process 4711 (java) total-vm:1234kB" {
+		t.Fatal("unexpected message: ", message)
+	}
+
+	// facility gets removed from the priority
+	if priority, _, _, _, err = parseKmsgRecord("30,1,2,-;systemd[1]: Started foo"); err != nil || priority != 6 {
+		t.Fatal("unexpected priority: ", priority, err)
+	}
+
+	for _, record := range []string{"no header", "3,1;missing timestamp", "x,1,2,-;invalid priority"} {
+		if _, _, _, _, err := parseKmsgRecord(record); err == nil {
+			t.Fatal("expected error for record: ", record)
+		}
+	}
+}
+
+func TestProcessKmsgRecords(t *testing.T) {
+	check := &CheckKernelEvents{
+		maxMessages: 2,
+	}
+	boot := time.Unix(1600000000, 0)
+
+	// the first run only remembers the position in the ring buffer
+	result := &resultKernelEvents{}
+	check.processKmsgRecords([]string{
+		"3,10,1000000,-;Out of memory: Killed process 1 (foo)",
+	}, boot, result)
+	check.initialized = true
+	if result.OomMessages != 0 || len(check.messages) != 0 || check.lastSeq != 10 {
+		t.Fatal("unexpected result of the first run: ", result, check.lastSeq)
+	}
+
+	result = &resultKernelEvents{}
+	check.processKmsgRecords([]string{
+		"3,10,1000000,-;Out of memory: Killed process 1 (foo)",
+		"6,11,2000000,-;e1000e: eth0 NIC Link is Up",
+		"3,12,3000000,-;INFO: task kworker:123 blocked for more than 120 seconds.",
+		"3,13,4000000,-;EXT4-fs error (device sda1): ext4_find_entry:1455: inode #2: comm ls: reading directory lblock 0",
+		"4,14,5000000,-;nginx invoked oom-killer: gfp_mask=0x100cca",
+	}, boot, result)
+
+	if result.OomMessages != 1 || result.HungTaskMessages != 1 || result.FilesystemErrorMessages != 1 {
+		t.Fatal("unexpected message counts: ", result)
+	}
+	if check.lastSeq != 14 {
+		t.Fatal("unexpected sequence: ", check.lastSeq)
+	}
+	if len(check.messages) != 2 {
+		t.Fatal("expected only the last 2 messages: ", len(check.messages))
+	}
+	if msg := check.messages[0]; msg.Type != kernelMessageFilesystemError || msg.Priority != 3 || msg.Timestamp != 1600000004 {
+		t.Fatal("unexpected message: ", msg)
+	}
+	if msg := check.messages[1]; msg.Type != kernelMessageOom || msg.Priority != 4 {
+		t.Fatal("unexpected message: ", msg)
+	}
+}
+
+func TestChecksCheckKernelEventsFixtures(t *testing.T) {
+	procPath := t.TempDir()
+	cgroupPath := t.TempDir()
+	writeFixtureFiles(t, procPath, map[string]string{"vmstat": "nr_free_pages 12345\noom_kill 2\n"})
+	writeFixtureFiles(t, cgroupPath, map[string]string{
+		"cgroup.controllers":                       "cpu io memory pids\n",
+		"system.slice/nginx.service/memory.events": "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n",
+	})
+
+	check := &CheckKernelEvents{
+		cgroups:    []string{"system.slice/*.service"},
+		procPath:   procPath,
+		cgroupPath: cgroupPath,
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultKernelEvents)
+	if !ok {
+		t.Fatal("False type")
+	}
+	name := filepath.Join("system.slice", "nginx.service")
+	if result.OomKill != 2 || result.OomKillDelta != 0 || result.Cgroups[name].OomKillEvents != 1 {
+		t.Fatal("unexpected oom values: ", result)
+	}
+
+	writeFixtureFiles(t, procPath, map[string]string{"vmstat": "nr_free_pages 12345\noom_kill 5\n"})
+	writeFixtureFiles(t, cgroupPath, map[string]string{"system.slice/nginx.service/memory.events": "oom 3\noom_kill 3\n"})
+
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(*resultKernelEvents)
+	if result.OomKillDelta != 3 || result.Cgroups[name].OomKillDelta != 2 {
+		t.Fatal("unexpected oom deltas: ", result.OomKillDelta, result.Cgroups[name].OomKillDelta)
+	}
+
+	// restarting the unit recreates the cgroup and resets memory.events
+	writeFixtureFiles(t, cgroupPath, map[string]string{"system.slice/nginx.service/memory.events": "oom 1\noom_kill 1\n"})
+
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(*resultKernelEvents)
+	if result.OomKillDelta != 0 || result.Cgroups[name].OomKillDelta != 1 {
+		t.Fatal("unexpected oom deltas after restart: ", result.OomKillDelta, result.Cgroups[name].OomKillDelta)
+	}
+}
+
+func TestChecksCheckKernelEvents(t *testing.T) {
+	check := &CheckKernelEvents{
+		cgroups: []string{"system.slice/*.service"},
+		kmsg:    true,
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
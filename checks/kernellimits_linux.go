@@ -0,0 +1,146 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/safemaths"
+)
+
+// CheckKernelLimits gathers the usage of host wide kernel limits like conntrack entries and file handles
+type CheckKernelLimits struct {
+	procPath string
+}
+
+// Name will be used in the response as check name
+func (c *CheckKernelLimits) Name() string {
+	return "kernel_limits"
+}
+
+type resultKernelLimit struct {
+	Used    uint64  `json:"used"`
+	Max     uint64  `json:"max"`
+	Percent float64 `json:"percent"`
+}
+
+type resultKernelLimits struct {
+	Conntrack   *resultKernelLimit `json:"conntrack"`    // nil if nf_conntrack is not loaded
+	FileHandles *resultKernelLimit `json:"file_handles"` // allocated file handles vs. fs.file-max
+	Pids        *resultKernelLimit `json:"pids"`         // every thread uses a PID so threads are compared with kernel.pid_max
+	Threads     *resultKernelLimit `json:"threads"`      // threads vs. kernel.threads-max
+	Processes   uint64             `json:"processes"`
+}
+
+func newKernelLimit(used, max uint64) *resultKernelLimit {
+	return &resultKernelLimit{
+		Used:    used,
+		Max:     max,
+		Percent: safemaths.DivideFloat64(float64(used), float64(max)) * 100.0,
+	}
+}
+
+func readProcUint64(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// parseFileNr parses /proc/sys/fs/file-nr: allocated, allocated but unused (always 0 since Linux 2.6) and maximum
+func parseFileNr(content string) (*resultKernelLimit, error) {
+	fields := strings.Fields(content)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected file-nr format: %s", content)
+	}
+	values := make([]uint64, 3)
+	for i, field := range fields {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file-nr format: %s", content)
+		}
+		values[i] = value
+	}
+	return newKernelLimit(values[0]-values[1], values[2]), nil
+}
+
+// parseLoadavgThreads returns the number of threads (scheduling entities) from /proc/loadavg e.g.: 0.20 0.18 0.12 1/80 11206
+func parseLoadavgThreads(content string) (uint64, error) {
+	fields := strings.Fields(content)
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("unexpected loadavg format: %s", content)
+	}
+	_, threads, found := strings.Cut(fields[3], "/")
+	if !found {
+		return 0, fmt.Errorf("unexpected loadavg format: %s", content)
+	}
+	return strconv.ParseUint(threads, 10, 64)
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckKernelLimits) Run(ctx context.Context) (interface{}, error) {
+	procPath := c.procPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+	result := &resultKernelLimits{}
+
+	// nf_conntrack is only available if the module is loaded
+	if count, err := readProcUint64(filepath.Join(procPath, "sys", "net", "netfilter", "nf_conntrack_count")); err == nil {
+		if max, err := readProcUint64(filepath.Join(procPath, "sys", "net", "netfilter", "nf_conntrack_max")); err == nil {
+			result.Conntrack = newKernelLimit(count, max)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(procPath, "sys", "fs", "file-nr"))
+	if err != nil {
+		return nil, err
+	}
+	if result.FileHandles, err = parseFileNr(string(content)); err != nil {
+		return nil, err
+	}
+
+	content, err = os.ReadFile(filepath.Join(procPath, "loadavg"))
+	if err != nil {
+		return nil, err
+	}
+	threads, err := parseLoadavgThreads(string(content))
+	if err != nil {
+		return nil, err
+	}
+	pidMax, err := readProcUint64(filepath.Join(procPath, "sys", "kernel", "pid_max"))
+	if err != nil {
+		return nil, err
+	}
+	threadsMax, err := readProcUint64(filepath.Join(procPath, "sys", "kernel", "threads-max"))
+	if err != nil {
+		return nil, err
+	}
+	result.Pids = newKernelLimit(threads, pidMax)
+	result.Threads = newKernelLimit(threads, threadsMax)
+
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if _, err := strconv.ParseUint(entry.Name(), 10, 32); err == nil && entry.IsDir() {
+			result.Processes++
+		}
+	}
+
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckKernelLimits) Configure(config *config.Configuration) (bool, error) {
+	return config.KernelLimits, nil
+}
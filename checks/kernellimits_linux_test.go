@@ -0,0 +1,100 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFileNr(t *testing.T) {
+	result, err := parseFileNr("9088\t1024\t100000\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Used != 8064 || result.Max != 100000 {
+		t.Fatal("unexpected values: ", result)
+	}
+
+	if _, err := parseFileNr("9088 0"); err == nil {
+		t.Fatal("expected error for invalid format")
+	}
+}
+
+func TestChecksCheckKernelLimitsFixtures(t *testing.T) {
+	procPath := t.TempDir()
+	writeFixtureFiles(t, procPath, map[string]string{
+		"loadavg":                "0.20 0.18 0.12 3/1600 11206\n",
+		"sys/fs/file-nr":         "25000\t0\t100000\n",
+		"sys/kernel/pid_max":     "32768\n",
+		"sys/kernel/threads-max": "6400\n",
+		"1/comm":                 "systemd\n",
+		"812/comm":               "nginx\n",
+		"self/comm":              "test\n",
+	})
+
+	check := &CheckKernelLimits{
+		procPath: procPath,
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultKernelLimits)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	if result.Conntrack != nil {
+		t.Fatal("conntrack should not be reported without nf_conntrack")
+	}
+	if result.FileHandles.Used != 25000 || result.FileHandles.Max != 100000 || result.FileHandles.Percent != 25 {
+		t.Fatal("unexpected file handles: ", result.FileHandles)
+	}
+	if result.Pids.Used != 1600 || result.Pids.Max != 32768 {
+		t.Fatal("unexpected pids: ", result.Pids)
+	}
+	if result.Threads.Used != 1600 || result.Threads.Max != 6400 || result.Threads.Percent != 25 {
+		t.Fatal("unexpected threads: ", result.Threads)
+	}
+	if result.Processes != 2 {
+		t.Fatal("expected 2 processes: ", result.Processes)
+	}
+
+	writeFixtureFiles(t, filepath.Join(procPath, "sys", "net", "netfilter"), map[string]string{
+		"nf_conntrack_count": "196608\n",
+		"nf_conntrack_max":   "262144\n",
+	})
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(*resultKernelLimits)
+	if result.Conntrack == nil || result.Conntrack.Used != 196608 || result.Conntrack.Max != 262144 || result.Conntrack.Percent != 75 {
+		t.Fatal("unexpected conntrack usage: ", result.Conntrack)
+	}
+
+	if err := os.Remove(filepath.Join(procPath, "loadavg")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := check.Run(context.Background()); err == nil {
+		t.Fatal("expected error without loadavg")
+	}
+}
+
+func TestChecksCheckKernelLimits(t *testing.T) {
+	check := &CheckKernelLimits{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
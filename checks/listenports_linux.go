@@ -0,0 +1,254 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+// CheckListenPorts gathers all listening TCP and UDP sockets with their owning process
+type CheckListenPorts struct {
+	// expected ports e.g.: tcp/22, udp/53
+	expected []string
+
+	procPath string
+}
+
+// Name will be used in the response as check name
+func (c *CheckListenPorts) Name() string {
+	return "listen_ports"
+}
+
+type resultListenSocket struct {
+	Protocol    string `json:"protocol"`     // tcp, tcp6, udp or udp6
+	Address     string `json:"address"`      // e.g.: 0.0.0.0, 127.0.0.1, ::
+	Port        uint16 `json:"port"`         // e.g.: 22
+	Pid         int32  `json:"pid"`          // 0 if the process could not be determined (requires root privileges)
+	ProcessName string `json:"process_name"` // e.g.: sshd
+}
+
+type resultListenPorts struct {
+	Sockets    []*resultListenSocket `json:"sockets"`
+	Expected   []string              `json:"expected"`   // Configured expected ports e.g.: tcp/22
+	Missing    []string              `json:"missing"`    // Expected ports without a listening socket
+	Unexpected []string              `json:"unexpected"` // Listening ports which are not expected (only if expected ports are configured)
+}
+
+// parseExpectedPort normalizes an expected port e.g.: 22 -> tcp/22, UDP/53 -> udp/53
+func parseExpectedPort(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	protocol, port, found := strings.Cut(value, "/")
+	if !found {
+		protocol, port = "tcp", value
+	}
+	if protocol != "tcp" && protocol != "udp" {
+		return "", fmt.Errorf("invalid protocol in expected port '%s', only tcp and udp are supported", value)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || p == 0 {
+		return "", fmt.Errorf("invalid expected port '%s'", value)
+	}
+	return fmt.Sprintf("%s/%d", protocol, p), nil
+}
+
+// localPortRange returns the range of ephemeral ports the kernel assigns to unbound sockets
+// Falls back to the default range of the kernel if ip_local_port_range can not be read
+func localPortRange(procPath string) (uint16, uint16) {
+	value, ok := readSysfsString(filepath.Join(procPath, "sys", "net", "ipv4", "ip_local_port_range"))
+	if ok {
+		fields := strings.Fields(value)
+		if len(fields) == 2 {
+			low, errLow := strconv.ParseUint(fields[0], 10, 16)
+			high, errHigh := strconv.ParseUint(fields[1], 10, 16)
+			if errLow == nil && errHigh == nil && low <= high {
+				return uint16(low), uint16(high)
+			}
+		}
+	}
+	return 32768, 60999
+}
+
+// socketInodeProcesses maps socket inodes to the pid of the owning process
+// Processes which can not be read (missing privileges, already exited) are skipped
+func socketInodeProcesses(ctx context.Context, procPath string, inodes map[uint64]bool) map[uint64]int32 {
+	result := map[uint64]int32{}
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return result
+	}
+	for _, entry := range entries {
+		if len(result) == len(inodes) || ctx.Err() != nil {
+			break
+		}
+		pid, err := strconv.ParseInt(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		fdPath := filepath.Join(procPath, entry.Name(), "fd")
+		fds, err := os.ReadDir(fdPath)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdPath, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil || !inodes[inode] {
+				continue
+			}
+			if _, ok := result[inode]; !ok {
+				result[inode] = int32(pid)
+			}
+		}
+	}
+	return result
+}
+
+// listenSummary compares the listening sockets with the expected ports
+func listenSummary(sockets []*resultListenSocket, expected []string) (missing, unexpected []string) {
+	missing = []string{}
+	unexpected = []string{}
+
+	listening := map[string]bool{}
+	for _, socket := range sockets {
+		listening[fmt.Sprintf("%s/%d", strings.TrimSuffix(socket.Protocol, "6"), socket.Port)] = true
+	}
+
+	expectedPorts := map[string]bool{}
+	for _, port := range expected {
+		expectedPorts[port] = true
+		if !listening[port] {
+			missing = append(missing, port)
+		}
+	}
+	if len(expected) == 0 {
+		return missing, unexpected
+	}
+	for port := range listening {
+		if !expectedPorts[port] {
+			unexpected = append(unexpected, port)
+		}
+	}
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckListenPorts) Run(ctx context.Context) (interface{}, error) {
+	procPath := c.procPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+
+	type listenSocket struct {
+		protocol string
+		socket   *tcpSocket
+	}
+	listening := []listenSocket{}
+	inodes := map[uint64]bool{}
+
+	// client sockets (DNS, NTP, ...) are also unconnected udp sockets, but use an ephemeral port
+	ephemeralLow, ephemeralHigh := localPortRange(procPath)
+	expectedPorts := map[string]bool{}
+	for _, port := range c.expected {
+		expectedPorts[port] = true
+	}
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		f, err := os.Open(filepath.Join(procPath, "net", protocol))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// IPv6 disabled
+				continue
+			}
+			return nil, err
+		}
+		// udp uses the same format as tcp
+		sockets, err := parseProcNetTCP(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, socket := range sockets {
+			if strings.HasPrefix(protocol, "tcp") && socket.State != "LISTEN" {
+				continue
+			}
+			// unconnected udp sockets are receiving from any remote address
+			if strings.HasPrefix(protocol, "udp") && (socket.RemotePort != 0 || !socket.RemoteIP.IsUnspecified()) {
+				continue
+			}
+			// udp sockets in the ephemeral port range are only reported if they are expected
+			if strings.HasPrefix(protocol, "udp") && socket.LocalPort >= ephemeralLow && socket.LocalPort <= ephemeralHigh && !expectedPorts[fmt.Sprintf("udp/%d", socket.LocalPort)] {
+				continue
+			}
+			listening = append(listening, listenSocket{protocol: protocol, socket: socket})
+			inodes[socket.Inode] = true
+		}
+	}
+
+	processes := socketInodeProcesses(ctx, procPath, inodes)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &resultListenPorts{
+		Sockets:  make([]*resultListenSocket, 0, len(listening)),
+		Expected: c.expected,
+	}
+	if result.Expected == nil {
+		result.Expected = []string{}
+	}
+	names := map[int32]string{}
+	for _, l := range listening {
+		socket := &resultListenSocket{
+			Protocol: l.protocol,
+			Address:  l.socket.LocalIP.String(),
+			Port:     l.socket.LocalPort,
+			Pid:      processes[l.socket.Inode],
+		}
+		if socket.Pid > 0 {
+			name, ok := names[socket.Pid]
+			if !ok {
+				name, _ = readSysfsString(filepath.Join(procPath, strconv.Itoa(int(socket.Pid)), "comm"))
+				names[socket.Pid] = name
+			}
+			socket.ProcessName = name
+		}
+		result.Sockets = append(result.Sockets, socket)
+	}
+	sort.SliceStable(result.Sockets, func(i, j int) bool {
+		if result.Sockets[i].Port != result.Sockets[j].Port {
+			return result.Sockets[i].Port < result.Sockets[j].Port
+		}
+		return result.Sockets[i].Protocol < result.Sockets[j].Protocol
+	})
+
+	result.Missing, result.Unexpected = listenSummary(result.Sockets, c.expected)
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckListenPorts) Configure(config *config.Configuration) (bool, error) {
+	c.expected = make([]string, 0, len(config.ListenPortsExpected))
+	for _, value := range config.ListenPortsExpected {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		port, err := parseExpectedPort(value)
+		if err != nil {
+			return false, err
+		}
+		c.expected = append(c.expected, port)
+	}
+	return config.ListenPorts, nil
+}
@@ -0,0 +1,162 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+const procNetUDPFixture = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 3500007F:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000   101        0 19001 2 0000000000000000 0
+  101: 0A00000A:A3F2 08080808:0035 01 00000000:00000000 00:00000000 00000000  1000        0 19002 2 0000000000000000 0
+  102: 00000000:B0F1 00000000:0000 07 00000000:00000000 00:00000000 00000000   102        0 19003 2 0000000000000000 0
+  103: 00000000:9C40 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 19004 2 0000000000000000 0
+`
+
+func writeProcessFixture(t *testing.T, procPath, pid, comm string, inodes ...string) {
+	writeFixtureFiles(t, filepath.Join(procPath, pid), map[string]string{"comm": comm + "\n"})
+	fdPath := filepath.Join(procPath, pid, "fd")
+	if err := os.MkdirAll(fdPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/dev/null", filepath.Join(fdPath, "0")); err != nil {
+		t.Fatal(err)
+	}
+	for i, inode := range inodes {
+		if err := os.Symlink("socket:["+inode+"]", filepath.Join(fdPath, fmt.Sprint(i+3))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParseExpectedPort(t *testing.T) {
+	for value, expected := range map[string]string{
+		"22":       "tcp/22",
+		" UDP/53 ": "udp/53",
+		"tcp/0443": "tcp/443",
+	} {
+		port, err := parseExpectedPort(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port != expected {
+			t.Fatal("unexpected port: ", value, port)
+		}
+	}
+
+	for _, value := range []string{"sctp/22", "tcp/ssh", "tcp/70000", "0"} {
+		if _, err := parseExpectedPort(value); err == nil {
+			t.Fatal("expected error for: ", value)
+		}
+	}
+}
+
+func TestChecksCheckListenPortsFixtures(t *testing.T) {
+	procPath := t.TempDir()
+	writeFixtureFiles(t, filepath.Join(procPath, "net"), map[string]string{
+		"tcp":  procNetTCPFixture,
+		"tcp6": procNetTCP6Fixture,
+		"udp":  procNetUDPFixture,
+	})
+	writeProcessFixture(t, procPath, "812", "nginx", "21034", "21035")
+	writeProcessFixture(t, procPath, "455", "systemd-resolve", "19001")
+	writeProcessFixture(t, procPath, "1200", "curl", "19002")
+	writeProcessFixture(t, procPath, "602", "chronyd", "19003")
+	writeProcessFixture(t, procPath, "700", "openvpn", "19004")
+	writeFixtureFiles(t, procPath, map[string]string{
+		"self/comm":                        "test\n",
+		"sys/net/ipv4/ip_local_port_range": "32768\t60999\n",
+	})
+
+	check := &CheckListenPorts{
+		procPath: procPath,
+	}
+	ok, err := check.Configure(&config.Configuration{
+		ListenPorts:         true,
+		ListenPortsExpected: []string{"tcp/80", "udp/53", "22", "udp/40000"},
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultListenPorts)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	// the unconnected udp client socket of chronyd on the ephemeral port 45297 is skipped,
+	// the expected udp port 40000 is reported even though it is in the ephemeral port range
+	if len(result.Sockets) != 5 {
+		t.Fatal("expected 5 listening sockets: ", len(result.Sockets))
+	}
+	if s := result.Sockets[0]; s.Protocol != "udp" || s.Address != "127.0.0.53" || s.Port != 53 || s.Pid != 455 || s.ProcessName != "systemd-resolve" {
+		t.Fatal("unexpected udp socket: ", s)
+	}
+	if s := result.Sockets[1]; s.Protocol != "tcp" || s.Address != "0.0.0.0" || s.Port != 80 || s.Pid != 812 || s.ProcessName != "nginx" {
+		t.Fatal("unexpected tcp socket: ", s)
+	}
+	if s := result.Sockets[2]; s.Protocol != "tcp6" || s.Address != "::" || s.Port != 80 || s.Pid != 812 {
+		t.Fatal("unexpected tcp6 socket: ", s)
+	}
+	if s := result.Sockets[3]; s.Port != 3306 || s.Address != "127.0.0.1" || s.Pid != 0 || s.ProcessName != "" {
+		t.Fatal("expected socket without process: ", s)
+	}
+	if s := result.Sockets[4]; s.Protocol != "udp" || s.Port != 40000 || s.ProcessName != "openvpn" {
+		t.Fatal("unexpected expected udp socket in the ephemeral port range: ", s)
+	}
+
+	if len(result.Missing) != 1 || result.Missing[0] != "tcp/22" {
+		t.Fatal("unexpected missing ports: ", result.Missing)
+	}
+	if len(result.Unexpected) != 1 || result.Unexpected[0] != "tcp/3306" {
+		t.Fatal("unexpected unexpected ports: ", result.Unexpected)
+	}
+}
+
+func TestLocalPortRange(t *testing.T) {
+	procPath := t.TempDir()
+	if low, high := localPortRange(procPath); low != 32768 || high != 60999 {
+		t.Fatal("expected default port range: ", low, high)
+	}
+	writeFixtureFiles(t, procPath, map[string]string{"sys/net/ipv4/ip_local_port_range": "1024\t65000\n"})
+	if low, high := localPortRange(procPath); low != 1024 || high != 65000 {
+		t.Fatal("unexpected port range: ", low, high)
+	}
+}
+
+func TestChecksCheckListenPortsWithoutExpected(t *testing.T) {
+	sockets := []*resultListenSocket{
+		{Protocol: "tcp", Port: 22},
+	}
+	missing, unexpected := listenSummary(sockets, nil)
+	if len(missing) != 0 || len(unexpected) != 0 {
+		t.Fatal("expected empty summary without expected ports: ", missing, unexpected)
+	}
+
+	if _, err := (&CheckListenPorts{}).Configure(&config.Configuration{ListenPortsExpected: []string{"tcp/ssh"}}); err == nil {
+		t.Fatal("expected error for invalid expected port")
+	}
+}
+
+func TestChecksCheckListenPorts(t *testing.T) {
+	check := &CheckListenPorts{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
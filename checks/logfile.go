@@ -0,0 +1,372 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxLogfileLineLength truncates long lines while reading, only the beginning of a line is matched and reported
+const maxLogfileLineLength = 1024
+
+// maxLogfileBytesPerRun limits the bytes read by a single check run, the rest is read on the next runs
+const maxLogfileBytesPerRun = 16 * 1024 * 1024
+
+type logfileMatcher struct {
+	config   *config.Logfile
+	critical *regexp.Regexp
+	warning  *regexp.Regexp
+}
+
+// logfileState is the read position of a single file
+type logfileState struct {
+	ID     uint64 `json:"id"` // inode (Windows: file index) to detect log rotation
+	Offset int64  `json:"offset"`
+	// Rotated file which was not read completely before the rotation
+	Rotated bool `json:"rotated,omitempty"`
+	// Offset is within a line which was counted already, the rest of it will be skipped
+	Partial bool `json:"partial,omitempty"`
+}
+
+// CheckLogfile counts new lines of log files matching a critical or warning regex
+type CheckLogfile struct {
+	logfiles  []*logfileMatcher
+	stateFile string
+	lines     int
+	// maxBytes per run, 0 uses maxLogfileBytesPerRun
+	maxBytes int64
+
+	// state per log file name and path
+	state map[string]map[string]*logfileState
+}
+
+// Name will be used in the response as check name
+func (c *CheckLogfile) Name() string {
+	return "logfiles"
+}
+
+type resultLogfileLine struct {
+	File string `json:"file"`
+	Line string `json:"line"`
+}
+
+type resultLogfile struct {
+	Files        []string             `json:"files"`    // Files matching the path or glob
+	Lines        uint64               `json:"lines"`    // Lines read since the last check
+	Critical     uint64               `json:"critical"` // Lines matching the critical regex since the last check
+	Warning      uint64               `json:"warning"`  // Lines matching the warning regex since the last check
+	Rotated      uint64               `json:"rotated"`  // Number of files which were rotated or truncated since the last check
+	Backlog      int64                `json:"backlog"`  // Bytes which were not read yet because of the read limit per check run
+	LastCritical []*resultLogfileLine `json:"last_critical"`
+	LastWarning  []*resultLogfileLine `json:"last_warning"`
+	Error        string               `json:"error"`
+}
+
+func loadLogfileState(path string) map[string]map[string]*logfileState {
+	state := map[string]map[string]*logfileState{}
+	if path == "" || utils.FileNotExists(path) {
+		return state
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Errorln("Log file state: could not read state file: ", err)
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Errorln("Log file state: could not parse state file: ", err)
+		return map[string]map[string]*logfileState{}
+	}
+	return state
+}
+
+func saveLogfileState(path string, state map[string]map[string]*logfileState) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("could not serialize log file state: %s", err)
+	}
+
+	// write to a temporary file first, so we never leave a partially written state file behind
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("could not write log file state file: %s", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("could not write log file state file: %s", err)
+	}
+	return nil
+}
+
+func appendLogfileLine(lines []*resultLogfileLine, max int, file, line string) []*resultLogfileLine {
+	if max <= 0 {
+		return lines
+	}
+	lines = append(lines, &resultLogfileLine{File: file, Line: line})
+	if len(lines) > max {
+		lines = lines[len(lines)-max:]
+	}
+	return lines
+}
+
+// readLogfileLine reads the next line, but keeps only the first maxLogfileLineLength bytes of it
+// Returns the number of bytes read and if the line is complete (ends with a line break)
+func readLogfileLine(reader *bufio.Reader) (string, int64, bool, error) {
+	var line []byte
+	var n int64
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		n += int64(len(chunk))
+		if room := maxLogfileLineLength - len(line); room > 0 {
+			if len(chunk) > room {
+				chunk = chunk[:room]
+			}
+			line = append(line, chunk...)
+		}
+		if err != bufio.ErrBufferFull {
+			return string(line), n, err == nil, err
+		}
+	}
+}
+
+// readLogfile reads all complete lines after the offset of state until the budget is used up and updates the state
+// An incomplete last line will be read again on the next run, unless final is set for rotated files
+// A line longer than the rest of the budget is counted right away and the rest of it is skipped on the next run
+func (c *CheckLogfile) readLogfile(ctx context.Context, path string, state *logfileState, final bool, budget *int64, matcher *logfileMatcher, result *resultLogfile) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(state.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	limited := &io.LimitedReader{R: f, N: *budget}
+	defer func() {
+		if limited.N <= 0 {
+			// nothing more is read from any file until the next run
+			*budget = 0
+		}
+	}()
+
+	reader := bufio.NewReader(limited)
+	for ctx.Err() == nil {
+		line, n, complete, err := readLogfileLine(reader)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+
+		skip := state.Partial
+		switch {
+		case complete:
+			state.Partial = false
+		case skip || (limited.N <= 0 && len(line) >= maxLogfileLineLength):
+			// rest of a counted line or a line longer than the budget
+			state.Partial = true
+		case final && limited.N > 0:
+			// last line of a rotated file without a line break
+		default:
+			// incomplete line
+			return nil
+		}
+		state.Offset += n
+		*budget -= n
+		if skip {
+			continue
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		result.Lines++
+		switch {
+		case matcher.critical != nil && matcher.critical.MatchString(line):
+			result.Critical++
+			result.LastCritical = appendLogfileLine(result.LastCritical, c.lines, path, line)
+		case matcher.warning != nil && matcher.warning.MatchString(line):
+			result.Warning++
+			result.LastWarning = appendLogfileLine(result.LastWarning, c.lines, path, line)
+		}
+	}
+	return nil
+}
+
+// readRotatedLogfile reads the rest of a rotated file, which was written between the last run and the rotation
+// Returns the state to continue on the next run, if the budget was used up
+func (c *CheckLogfile) readRotatedLogfile(ctx context.Context, path string, last *logfileState, budget *int64, matcher *logfileMatcher, result *resultLogfile) *logfileState {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || logfileID(path, info) != last.ID {
+		// rotated file is gone, compressed or uses a different naming scheme
+		return nil
+	}
+	state := &logfileState{ID: last.ID, Offset: last.Offset, Rotated: true, Partial: last.Partial}
+	if err := c.readLogfile(ctx, path, state, true, budget, matcher, result); err != nil {
+		result.Error = err.Error()
+		return nil
+	}
+	if state.Offset >= info.Size() {
+		return nil
+	}
+	result.Backlog += info.Size() - state.Offset
+	return state
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckLogfile) Run(ctx context.Context) (interface{}, error) {
+	if c.state == nil {
+		c.state = loadLogfileState(c.stateFile)
+	}
+
+	budget := c.maxBytes
+	if budget <= 0 {
+		budget = maxLogfileBytesPerRun
+	}
+
+	results := make(map[string]*resultLogfile, len(c.logfiles))
+	newState := make(map[string]map[string]*logfileState, len(c.logfiles))
+	for _, matcher := range c.logfiles {
+		name := matcher.config.Name
+		result := &resultLogfile{
+			Files:        []string{},
+			LastCritical: []*resultLogfileLine{},
+			LastWarning:  []*resultLogfileLine{},
+		}
+		results[name] = result
+
+		known, seen := c.state[name]
+		files := map[string]*logfileState{}
+		newState[name] = files
+
+		paths, err := filepath.Glob(matcher.config.File)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		globPaths := make(map[string]bool, len(paths))
+		for _, path := range paths {
+			globPaths[path] = true
+		}
+		// files renamed within the glob keep their inode and are continued at the known offset
+		knownIDs := make(map[uint64]*logfileState, len(known))
+		for path, state := range known {
+			if state.ID != 0 {
+				knownIDs[state.ID] = state
+			}
+			// rotated files of previous runs with lines which were not read yet
+			if state.Rotated && !globPaths[path] {
+				if rotated := c.readRotatedLogfile(ctx, path, state, &budget, matcher, result); rotated != nil {
+					files[path] = rotated
+				}
+			}
+		}
+
+		for _, path := range paths {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			result.Files = append(result.Files, path)
+			id := logfileID(path, info)
+
+			state := &logfileState{ID: id}
+			last := known[path]
+			switch {
+			case last == nil && !seen:
+				// do not report old lines of files which exist on the first run
+				state.Offset = info.Size()
+			case last == nil && id != 0 && knownIDs[id] != nil:
+				// file was renamed e.g.: app.log -> app.log.1 with a glob of app.log*
+				state.Offset = knownIDs[id].Offset
+				state.Partial = knownIDs[id].Partial
+			case last == nil:
+				// new file (e.g. after rotation)
+			case last.ID != id || info.Size() < last.Offset:
+				result.Rotated++
+				rotatedPath := path + ".1"
+				if last.ID != id && !globPaths[rotatedPath] {
+					// finish the rotated file first, otherwise the lines written since the last run would be lost
+					if rotated := c.readRotatedLogfile(ctx, rotatedPath, last, &budget, matcher, result); rotated != nil {
+						files[rotatedPath] = rotated
+					}
+				}
+			default:
+				state.Offset = last.Offset
+				state.Partial = last.Partial
+			}
+			if state.Offset > info.Size() {
+				// renamed file which was truncated
+				state.Offset = 0
+				state.Partial = false
+			}
+
+			if err := c.readLogfile(ctx, path, state, false, &budget, matcher, result); err != nil {
+				result.Error = err.Error()
+			}
+			if state.Offset < info.Size() {
+				result.Backlog += info.Size() - state.Offset
+			}
+			files[path] = state
+		}
+		if len(result.Files) == 0 && result.Error == "" {
+			result.Error = fmt.Sprintf("no file matches %s", matcher.config.File)
+		}
+	}
+
+	if !reflect.DeepEqual(newState, c.state) {
+		if err := saveLogfileState(c.stateFile, newState); err != nil {
+			log.Errorln("Log file state: ", err)
+		}
+	}
+	c.state = newState
+
+	return results, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckLogfile) Configure(config *config.Configuration) (bool, error) {
+	c.logfiles = make([]*logfileMatcher, 0, len(config.LogfileConfiguration))
+	for _, logfile := range config.LogfileConfiguration {
+		matcher := &logfileMatcher{
+			config: logfile,
+		}
+		if logfile.Critical != "" {
+			regex, err := regexp.Compile(logfile.Critical)
+			if err != nil {
+				return false, err
+			}
+			matcher.critical = regex
+		}
+		if logfile.Warning != "" {
+			regex, err := regexp.Compile(logfile.Warning)
+			if err != nil {
+				return false, err
+			}
+			matcher.warning = regex
+		}
+		c.logfiles = append(c.logfiles, matcher)
+	}
+	c.stateFile = config.LogfilesStateFile
+	c.lines = int(config.LogfilesLines)
+	return config.Logfiles, nil
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package checks
+
+import (
+	"os"
+	"syscall"
+)
+
+// logfileID returns the inode of the file to detect log rotation
+func logfileID(path string, info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,235 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+func appendLogfile(t *testing.T, path, content string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newLogfileCheck(t *testing.T, dir string) *CheckLogfile {
+	check := &CheckLogfile{}
+	ok, err := check.Configure(&config.Configuration{
+		Logfiles:          true,
+		LogfilesStateFile: filepath.Join(dir, "logfiles_state.json"),
+		LogfilesLines:     2,
+		LogfileConfiguration: []*config.Logfile{
+			{
+				Name:     "app",
+				Enabled:  true,
+				File:     filepath.Join(dir, "*.log"),
+				Critical: "FATAL",
+				Warning:  "ERROR|WARN",
+			},
+		},
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+	return check
+}
+
+func runLogfileCheck(t *testing.T, check *CheckLogfile) *resultLogfile {
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, ok := cr.(map[string]*resultLogfile)
+	if !ok {
+		t.Fatal("False type")
+	}
+	result, ok := results["app"]
+	if !ok {
+		t.Fatal("missing result for app")
+	}
+	return result
+}
+
+func TestChecksCheckLogfile(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	appendLogfile(t, logPath, "FATAL old line which was written before the agent was started\n")
+
+	check := newLogfileCheck(t, dir)
+	result := runLogfileCheck(t, check)
+	if len(result.Files) != 1 || result.Lines != 0 || result.Critical != 0 || result.Error != "" {
+		t.Fatal("expected existing lines to be skipped on the first run: ", result)
+	}
+
+	appendLogfile(t, logPath, "INFO started\nWARN disk almost full\nERROR connection refused\nFATAL out of memory\nERROR incomplete")
+	result = runLogfileCheck(t, check)
+	if result.Lines != 4 || result.Critical != 1 || result.Warning != 2 {
+		t.Fatal("unexpected counts: ", result.Lines, result.Critical, result.Warning)
+	}
+	if len(result.LastWarning) != 2 || result.LastWarning[1].Line != "ERROR connection refused" || result.LastWarning[1].File != logPath {
+		t.Fatal("unexpected last warning lines: ", result.LastWarning)
+	}
+	if len(result.LastCritical) != 1 || result.LastCritical[0].Line != "FATAL out of memory" {
+		t.Fatal("unexpected last critical lines: ", result.LastCritical)
+	}
+
+	appendLogfile(t, logPath, " line\n")
+	result = runLogfileCheck(t, check)
+	if result.Lines != 1 || result.Warning != 1 || result.LastWarning[0].Line != "ERROR incomplete line" {
+		t.Fatal("expected the incomplete line to be read again: ", result.LastWarning)
+	}
+
+	// a new check instance (agent restart) continues at the stored offset
+	check = newLogfileCheck(t, dir)
+	appendLogfile(t, logPath, "FATAL after restart\n")
+	result = runLogfileCheck(t, check)
+	if result.Lines != 1 || result.Critical != 1 || result.Rotated != 0 {
+		t.Fatal("expected only the new line after restart: ", result.Lines, result.Critical)
+	}
+
+	// rotation
+	if err := os.Rename(logPath, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	appendLogfile(t, logPath, "FATAL in new file\nmore lines than before\nand another line\n")
+	result = runLogfileCheck(t, check)
+	if result.Rotated != 1 || result.Lines != 3 || result.Critical != 1 {
+		t.Fatal("expected the rotated file to be read from the beginning: ", result.Rotated, result.Lines, result.Critical)
+	}
+
+	// truncate
+	if err := os.WriteFile(logPath, []byte("WARN truncated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result = runLogfileCheck(t, check)
+	if result.Rotated != 1 || result.Lines != 1 || result.Warning != 1 {
+		t.Fatal("expected the truncated file to be read from the beginning: ", result.Rotated, result.Lines, result.Warning)
+	}
+
+	// a new file matching the glob is read from the beginning
+	appendLogfile(t, filepath.Join(dir, "worker.log"), "FATAL worker crashed\n")
+	result = runLogfileCheck(t, check)
+	if len(result.Files) != 2 || result.Critical != 1 {
+		t.Fatal("expected new file to be read from the beginning: ", result.Files, result.Critical)
+	}
+
+	js, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
+
+func TestChecksCheckLogfileMissing(t *testing.T) {
+	dir := t.TempDir()
+	check := newLogfileCheck(t, dir)
+	result := runLogfileCheck(t, check)
+	if result.Error == "" || len(result.Files) != 0 {
+		t.Fatal("expected error for missing log file: ", result)
+	}
+
+	appendLogfile(t, filepath.Join(dir, "app.log"), "FATAL first line\n")
+	result = runLogfileCheck(t, check)
+	if result.Error != "" || result.Critical != 1 {
+		t.Fatal("expected file created after the first run to be read from the beginning: ", result)
+	}
+}
+
+func TestChecksCheckLogfileRotatedLines(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	appendLogfile(t, logPath, "INFO started\n")
+
+	check := newLogfileCheck(t, dir)
+	runLogfileCheck(t, check)
+
+	// lines written between the last run and the rotation
+	appendLogfile(t, logPath, "FATAL before rotation\nWARN last line without newline")
+	if err := os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLogfile(t, logPath, "ERROR after rotation\n")
+
+	result := runLogfileCheck(t, check)
+	if result.Rotated != 1 || result.Lines != 3 || result.Critical != 1 || result.Warning != 2 {
+		t.Fatal("expected the rest of the rotated file to be read: ", result.Rotated, result.Lines, result.Critical, result.Warning)
+	}
+	if result.LastCritical[0].File != logPath+".1" || result.LastWarning[0].Line != "WARN last line without newline" {
+		t.Fatal("unexpected lines of the rotated file: ", result.LastCritical, result.LastWarning)
+	}
+}
+
+func TestChecksCheckLogfileReadLimit(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	appendLogfile(t, logPath, "")
+
+	check := newLogfileCheck(t, dir)
+	check.maxBytes = 30
+	runLogfileCheck(t, check)
+
+	// every line has 10 bytes
+	appendLogfile(t, logPath, "FATAL 001\nFATAL 002\nFATAL 003\nFATAL 004\nFATAL 005\n")
+	result := runLogfileCheck(t, check)
+	if result.Lines != 3 || result.Critical != 3 || result.Backlog != 20 {
+		t.Fatal("expected the read limit to be used: ", result.Lines, result.Critical, result.Backlog)
+	}
+
+	// the rest of the rotated file is read before the new file
+	if err := os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLogfile(t, logPath, "WARN 0001\nWARN 0002\n")
+	result = runLogfileCheck(t, check)
+	if result.Rotated != 1 || result.Critical != 2 || result.Warning != 1 || result.LastCritical[1].Line != "FATAL 005" || result.Backlog != 10 {
+		t.Fatal("expected the rotated file to be read first: ", result.Critical, result.Warning, result.Backlog)
+	}
+
+	result = runLogfileCheck(t, check)
+	if result.Warning != 1 || result.LastWarning[0].Line != "WARN 0002" || result.Backlog != 0 {
+		t.Fatal("expected the rest of the new file: ", result.Warning, result.Backlog)
+	}
+}
+
+func TestChecksCheckLogfileLongLines(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	appendLogfile(t, logPath, "")
+
+	check := newLogfileCheck(t, dir)
+	check.maxBytes = 4096
+	runLogfileCheck(t, check)
+
+	// the line is longer than the read limit, the warning at its end is never matched
+	longLine := "FATAL " + strings.Repeat("x", 10000) + " WARN\n"
+	appendLogfile(t, logPath, longLine+"ERROR short line\n")
+	result := runLogfileCheck(t, check)
+	if result.Lines != 1 || result.Critical != 1 || len(result.LastCritical[0].Line) != maxLogfileLineLength {
+		t.Fatal("expected the beginning of the long line: ", result.Lines, result.Critical, result.LastCritical)
+	}
+	if result.Backlog != int64(len(longLine))+17-4096 {
+		t.Fatal("unexpected backlog: ", result.Backlog)
+	}
+
+	// the rest of the long line is skipped
+	result = runLogfileCheck(t, check)
+	if result.Lines != 0 || result.Critical != 0 || result.Warning != 0 {
+		t.Fatal("expected the long line to be skipped: ", result.Lines, result.Critical, result.Warning)
+	}
+
+	result = runLogfileCheck(t, check)
+	if result.Lines != 1 || result.Warning != 1 || result.LastWarning[0].Line != "ERROR short line" || result.Backlog != 0 {
+		t.Fatal("expected the line after the long line: ", result.Lines, result.Warning, result.Backlog)
+	}
+}
@@ -0,0 +1,22 @@
+package checks
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// logfileID returns the NTFS file index of the file to detect log rotation
+func logfileID(path string, info os.FileInfo) uint64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	var fileInfo windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(windows.Handle(f.Fd()), &fileInfo); err != nil {
+		return 0
+	}
+	return uint64(fileInfo.FileIndexHigh)<<32 | uint64(fileInfo.FileIndexLow)
+}
@@ -0,0 +1,253 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+// CheckMdraid gathers information about Linux software RAID arrays (mdraid)
+type CheckMdraid struct {
+	procPath string
+	sysPath  string
+}
+
+// Name will be used in the response as check name
+func (c *CheckMdraid) Name() string {
+	return "mdraid"
+}
+
+type resultMdraid struct {
+	Name          string   `json:"name"`           // e.g.: md0
+	Level         string   `json:"level"`          // e.g.: raid1, raid5, raid10
+	State         string   `json:"state"`          // e.g.: clean, active, inactive, read-auto
+	TotalDevices  int64    `json:"total_devices"`  // Number of devices the array is built of
+	ActiveDevices int64    `json:"active_devices"` // Number of working devices
+	FailedDevices int64    `json:"failed_devices"` // Number of faulty devices
+	SpareDevices  int64    `json:"spare_devices"`  // Number of spare devices
+	Degraded      bool     `json:"degraded"`       // True if less devices than required are working
+	SyncAction    string   `json:"sync_action"`    // idle, resync, recover, check, repair, reshape, delayed or pending
+	SyncPercent   float64  `json:"sync_percent"`   // Progress of the current sync action
+	SyncSpeed     uint64   `json:"sync_speed"`     // Speed of the current sync action in byte per second
+	SyncFinish    int64    `json:"sync_finish"`    // Estimated remaining time of the current sync action in seconds
+	Devices       []string `json:"devices"`        // e.g.: ["sda1","sdb1"]
+	FailedDevs    []string `json:"failed_devs"`    // e.g.: ["sdc1"]
+	SpareDevs     []string `json:"spare_devs"`     // e.g.: ["sdd1"]
+}
+
+var (
+	mdstatStatusRegexp   = regexp.MustCompile(`\[(\d+)/(\d+)\]`)
+	mdstatProgressRegexp = regexp.MustCompile(`(resync|recovery|reshape|check|repair)\s*=\s*([\d.]+)%`)
+	mdstatFinishRegexp   = regexp.MustCompile(`finish=([\d.]+)min`)
+	mdstatSpeedRegexp    = regexp.MustCompile(`speed=(\d+)K/sec`)
+	mdstatDelayedRegexp  = regexp.MustCompile(`(resync|recovery|reshape|check|repair)\s*=\s*(DELAYED|PENDING)`)
+)
+
+// parseMdstat parses the content of /proc/mdstat
+func parseMdstat(r io.Reader) (map[string]*resultMdraid, error) {
+	results := make(map[string]*resultMdraid)
+
+	var current *resultMdraid
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			current = nil
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") {
+			name, rest, found := strings.Cut(line, " : ")
+			if !found || !strings.HasPrefix(name, "md") {
+				// Personalities or unused devices
+				current = nil
+				continue
+			}
+			current = parseMdstatArray(strings.TrimSpace(name), strings.Fields(rest))
+			results[current.Name] = current
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if match := mdstatStatusRegexp.FindStringSubmatch(trimmed); match != nil {
+			current.TotalDevices, _ = strconv.ParseInt(match[1], 10, 64)
+			current.ActiveDevices, _ = strconv.ParseInt(match[2], 10, 64)
+		}
+		if match := mdstatProgressRegexp.FindStringSubmatch(trimmed); match != nil {
+			current.SyncAction = mdstatSyncAction(match[1])
+			current.SyncPercent, _ = strconv.ParseFloat(match[2], 64)
+			if match := mdstatFinishRegexp.FindStringSubmatch(trimmed); match != nil {
+				minutes, _ := strconv.ParseFloat(match[1], 64)
+				current.SyncFinish = int64(minutes * 60)
+			}
+			if match := mdstatSpeedRegexp.FindStringSubmatch(trimmed); match != nil {
+				speed, _ := strconv.ParseUint(match[1], 10, 64)
+				current.SyncSpeed = speed * 1024
+			}
+		} else if match := mdstatDelayedRegexp.FindStringSubmatch(trimmed); match != nil {
+			current.SyncAction = strings.ToLower(match[2])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		if result.TotalDevices == 0 {
+			// raid0 and linear arrays do not report [n/m]
+			result.TotalDevices = int64(len(result.Devices))
+			result.ActiveDevices = int64(len(result.Devices))
+		}
+		result.Degraded = result.ActiveDevices < result.TotalDevices
+	}
+	return results, nil
+}
+
+// parseMdstatArray parses the first line of an array e.g.: md0 : active raid1 sdb1[1] sda1[0](F)
+func parseMdstatArray(name string, fields []string) *resultMdraid {
+	result := &resultMdraid{
+		Name:       name,
+		SyncAction: "idle",
+		Devices:    []string{},
+		FailedDevs: []string{},
+		SpareDevs:  []string{},
+	}
+	for i, field := range fields {
+		switch {
+		case i == 0:
+			result.State = field
+		case strings.HasPrefix(field, "("):
+			// (read-only) or (auto-read-only)
+			result.State = strings.Trim(field, "()")
+		case !strings.Contains(field, "["):
+			result.Level = field
+		default:
+			device, _, _ := strings.Cut(field, "[")
+			switch {
+			case strings.HasSuffix(field, "(F)"):
+				result.FailedDevs = append(result.FailedDevs, device)
+				result.FailedDevices++
+			case strings.HasSuffix(field, "(S)"):
+				result.SpareDevs = append(result.SpareDevs, device)
+				result.SpareDevices++
+			default:
+				result.Devices = append(result.Devices, device)
+			}
+		}
+	}
+	return result
+}
+
+// mdstatSyncAction converts the names used in /proc/mdstat to the names of sync_action in sysfs
+func mdstatSyncAction(action string) string {
+	if action == "recovery" {
+		return "recover"
+	}
+	return action
+}
+
+func readSysfsString(path string) (string, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(content)), true
+}
+
+// readMdraidSysfs updates the result with the more precise values of /sys/block/<md>/md
+func readMdraidSysfs(mdPath string, result *resultMdraid) {
+	if state, ok := readSysfsString(filepath.Join(mdPath, "array_state")); ok && state != "" {
+		result.State = state
+	}
+	if level, ok := readSysfsString(filepath.Join(mdPath, "level")); ok && level != "" {
+		result.Level = level
+	}
+	if value, ok := readSysfsString(filepath.Join(mdPath, "raid_disks")); ok {
+		if raidDisks, err := strconv.ParseInt(value, 10, 64); err == nil && raidDisks > 0 {
+			result.TotalDevices = raidDisks
+		}
+	}
+	if value, ok := readSysfsString(filepath.Join(mdPath, "degraded")); ok {
+		if degraded, err := strconv.ParseInt(value, 10, 64); err == nil {
+			result.ActiveDevices = result.TotalDevices - degraded
+			result.Degraded = degraded > 0
+		}
+	}
+
+	action, ok := readSysfsString(filepath.Join(mdPath, "sync_action"))
+	if !ok || action == "idle" || action == "frozen" {
+		return
+	}
+	result.SyncAction = action
+	if value, ok := readSysfsString(filepath.Join(mdPath, "sync_completed")); ok {
+		// e.g.: 132096 / 1046528 or none
+		done, total, found := strings.Cut(value, "/")
+		if found {
+			d, err1 := strconv.ParseFloat(strings.TrimSpace(done), 64)
+			t, err2 := strconv.ParseFloat(strings.TrimSpace(total), 64)
+			if err1 == nil && err2 == nil && t > 0 {
+				result.SyncPercent = d / t * 100.0
+			}
+		}
+	}
+	if value, ok := readSysfsString(filepath.Join(mdPath, "sync_speed")); ok {
+		// K/sec
+		if speed, err := strconv.ParseUint(value, 10, 64); err == nil {
+			result.SyncSpeed = speed * 1024
+		}
+	}
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckMdraid) Run(ctx context.Context) (interface{}, error) {
+	procPath := c.procPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+	sysPath := c.sysPath
+	if sysPath == "" {
+		sysPath = "/sys"
+	}
+
+	f, err := os.Open(filepath.Join(procPath, "mdstat"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// md driver not loaded
+			return make(map[string]*resultMdraid), nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	results, err := parseMdstat(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, result := range results {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		readMdraidSysfs(filepath.Join(sysPath, "block", name, "md"), result)
+	}
+
+	return results, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckMdraid) Configure(config *config.Configuration) (bool, error) {
+	return config.Mdraid, nil
+}
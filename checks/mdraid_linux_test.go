@@ -0,0 +1,142 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mdstatFixture = `Personalities : [raid1] [raid6] [raid5] [raid4] [raid0]
+md0 : active raid1 sdb1[1] sda1[0]
+      1046528 blocks super 1.2 [2/2] [UU]
+      bitmap: 0/1 pages [0KB], 65536KB chunk
+
+md1 : active raid5 sdd1[3](F) sdc1[2] sdb2[1] sda2[0](S)
+      2093056 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/2] [UU_]
+      [==>..................]  recovery = 12.6% (132096/1046528) finish=0.5min speed=26419K/sec
+
+md2 : active raid0 sdf1[1] sde1[0]
+      2093056 blocks super 1.2 512k chunks
+
+md3 : active (auto-read-only) raid1 sdh1[1] sdg1[0]
+      1046528 blocks super 1.2 [2/2] [UU]
+      	resync=PENDING
+
+md127 : inactive sdi[0](S)
+      1046528 blocks super 1.2
+
+unused devices: <none>
+`
+
+func TestParseMdstat(t *testing.T) {
+	results, err := parseMdstat(strings.NewReader(mdstatFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 {
+		t.Fatal("expected 5 arrays: ", len(results))
+	}
+
+	md0 := results["md0"]
+	if md0.Level != "raid1" || md0.State != "active" || md0.TotalDevices != 2 || md0.ActiveDevices != 2 || md0.Degraded {
+		t.Fatal("unexpected values for md0: ", md0)
+	}
+	if md0.SyncAction != "idle" || len(md0.Devices) != 2 {
+		t.Fatal("unexpected sync or devices for md0: ", md0)
+	}
+
+	md1 := results["md1"]
+	if md1.Level != "raid5" || md1.TotalDevices != 3 || md1.ActiveDevices != 2 || !md1.Degraded {
+		t.Fatal("unexpected values for md1: ", md1)
+	}
+	if md1.FailedDevices != 1 || md1.FailedDevs[0] != "sdd1" || md1.SpareDevices != 1 || md1.SpareDevs[0] != "sda2" {
+		t.Fatal("unexpected failed or spare devices for md1: ", md1)
+	}
+	if md1.SyncAction != "recover" || md1.SyncPercent != 12.6 || md1.SyncFinish != 30 || md1.SyncSpeed != 26419*1024 {
+		t.Fatal("unexpected sync values for md1: ", md1)
+	}
+
+	if md2 := results["md2"]; md2.Level != "raid0" || md2.TotalDevices != 2 || md2.ActiveDevices != 2 || md2.Degraded {
+		t.Fatal("unexpected values for md2: ", md2)
+	}
+	if md3 := results["md3"]; md3.State != "auto-read-only" || md3.SyncAction != "pending" {
+		t.Fatal("unexpected values for md3: ", md3)
+	}
+	if md127 := results["md127"]; md127.State != "inactive" || md127.Level != "" || md127.SpareDevices != 1 {
+		t.Fatal("unexpected values for md127: ", md127)
+	}
+}
+
+func TestChecksCheckMdraidFixtures(t *testing.T) {
+	procPath := t.TempDir()
+	sysPath := t.TempDir()
+	writeFixtureFiles(t, procPath, map[string]string{"mdstat": mdstatFixture})
+
+	md1 := filepath.Join(sysPath, "block", "md1", "md")
+	writeFixtureFiles(t, md1, map[string]string{
+		"array_state":    "clean\n",
+		"level":          "raid5\n",
+		"raid_disks":     "3\n",
+		"degraded":       "1\n",
+		"sync_action":    "recover\n",
+		"sync_completed": "523264 / 1046528\n",
+		"sync_speed":     "30000\n",
+	})
+	writeFixtureFiles(t, filepath.Join(sysPath, "block", "md0", "md"), map[string]string{
+		"array_state": "active\n",
+		"degraded":    "0\n",
+		"sync_action": "idle\n",
+	})
+
+	check := &CheckMdraid{
+		procPath: procPath,
+		sysPath:  sysPath,
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, ok := cr.(map[string]*resultMdraid)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	result := results["md1"]
+	if result.State != "clean" || result.ActiveDevices != 2 || !result.Degraded {
+		t.Fatal("unexpected values for md1: ", result)
+	}
+	if result.SyncAction != "recover" || result.SyncPercent != 50.0 || result.SyncSpeed != 30000*1024 {
+		t.Fatal("unexpected sync values for md1: ", result)
+	}
+	if result := results["md0"]; result.Degraded || result.SyncAction != "idle" {
+		t.Fatal("unexpected values for md0: ", result)
+	}
+
+	// systems without the md driver
+	check.procPath = t.TempDir()
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cr.(map[string]*resultMdraid)) != 0 {
+		t.Fatal("expected no arrays")
+	}
+}
+
+func TestChecksCheckMdraid(t *testing.T) {
+	check := &CheckMdraid{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
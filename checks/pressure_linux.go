@@ -0,0 +1,212 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/safemaths"
+)
+
+// CheckPressure gathers pressure stall information (PSI) of the system and configured cgroups
+// https://docs.kernel.org/accounting/psi.html
+type CheckPressure struct {
+	cgroups []string
+
+	procPath      string
+	cgroupPath    string
+	lastTimestamp time.Time
+	lastTotals    map[string]uint64
+}
+
+// Name will be used in the response as check name
+func (c *CheckPressure) Name() string {
+	return "pressure"
+}
+
+var pressureResources = []string{"cpu", "memory", "io"}
+
+type resultPressureLine struct {
+	Avg10        float64 `json:"avg10"`         // Share of time in percent some (or all) tasks were stalled in the last 10 seconds
+	Avg60        float64 `json:"avg60"`         // Share of time in percent some (or all) tasks were stalled in the last 60 seconds
+	Avg300       float64 `json:"avg300"`        // Share of time in percent some (or all) tasks were stalled in the last 300 seconds
+	Total        uint64  `json:"total"`         // Total stall time in microseconds (Counter)
+	TotalDelta   uint64  `json:"total_delta"`   // Stall time in microseconds since the last check evaluation
+	StallPercent float64 `json:"stall_percent"` // Share of time in percent tasks were stalled since the last check evaluation
+}
+
+type resultPressureResource struct {
+	Some *resultPressureLine `json:"some"`
+	Full *resultPressureLine `json:"full"` // nil for cpu on older kernels
+}
+
+type resultPressure struct {
+	Timestamp int64                                         `json:"timestamp"` // Timestamp of the last check evaluation
+	Available bool                                          `json:"available"` // false if the kernel does not support PSI
+	System    map[string]*resultPressureResource            `json:"system"`    // cpu, memory and io pressure of the whole system
+	Cgroups   map[string]map[string]*resultPressureResource `json:"cgroups"`   // cpu, memory and io pressure per configured cgroup
+}
+
+// parsePressure parses the content of /proc/pressure/<resource> or <cgroup>/<resource>.pressure
+func parsePressure(r io.Reader) (*resultPressureResource, error) {
+	result := &resultPressureResource{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		line := &resultPressureLine{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid pressure field: %s", field)
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				line.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				line.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				line.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				line.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid pressure field %s: %s", field, err)
+			}
+		}
+
+		switch fields[0] {
+		case "some":
+			result.Some = line
+		case "full":
+			result.Full = line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if result.Some == nil {
+		return nil, fmt.Errorf("missing some line in pressure information")
+	}
+	return result, nil
+}
+
+func readPressureFile(path string) (*resultPressureResource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parsePressure(f)
+}
+
+// pressureUnavailable returns true if the kernel does not provide pressure information
+// With psi=0 or CONFIG_PSI_DEFAULT_DISABLED the files exist, but reading them fails with EOPNOTSUPP
+func pressureUnavailable(err error) bool {
+	return os.IsNotExist(err) || errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.EINVAL)
+}
+
+// calculateDeltas sets the stall time since the last check evaluation
+func (c *CheckPressure) calculateDeltas(key string, resource *resultPressureResource, totals map[string]uint64, interval float64) {
+	for name, line := range map[string]*resultPressureLine{"some": resource.Some, "full": resource.Full} {
+		if line == nil {
+			continue
+		}
+		lineKey := key + "/" + name
+		if last, ok := c.lastTotals[lineKey]; ok && interval > 0 {
+			line.TotalDelta = ResetDiffUint64(last, line.Total)
+			// total is in microseconds
+			line.StallPercent = safemaths.DivideFloat64(float64(line.TotalDelta), interval*1000000.0) * 100.0
+			if line.StallPercent > 100.0 {
+				line.StallPercent = 100.0
+			}
+		}
+		totals[lineKey] = line.Total
+	}
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckPressure) Run(ctx context.Context) (interface{}, error) {
+	procPath := c.procPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+	cgroupPath := c.cgroupPath
+	if cgroupPath == "" {
+		cgroupPath = "/sys/fs/cgroup"
+	}
+
+	now := time.Now()
+	interval := 0.0
+	if !c.lastTimestamp.IsZero() {
+		interval = now.Sub(c.lastTimestamp).Seconds()
+	}
+	totals := map[string]uint64{}
+
+	result := &resultPressure{
+		Timestamp: now.Unix(),
+		System:    map[string]*resultPressureResource{},
+		Cgroups:   map[string]map[string]*resultPressureResource{},
+	}
+
+	for _, resource := range pressureResources {
+		pressure, err := readPressureFile(filepath.Join(procPath, "pressure", resource))
+		if err != nil {
+			if pressureUnavailable(err) {
+				// kernel older than 4.20 or PSI disabled (psi=0)
+				continue
+			}
+			return nil, err
+		}
+		c.calculateDeltas("system/"+resource, pressure, totals, interval)
+		result.System[resource] = pressure
+		result.Available = true
+	}
+
+	if root := cgroupV2Root(cgroupPath); root != "" && len(c.cgroups) > 0 {
+		for _, cgroup := range cgroupsForPatterns(root, c.cgroups) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			resources := map[string]*resultPressureResource{}
+			for _, resource := range pressureResources {
+				pressure, err := readPressureFile(filepath.Join(root, cgroup, resource+".pressure"))
+				if err != nil {
+					continue
+				}
+				c.calculateDeltas(cgroup+"/"+resource, pressure, totals, interval)
+				resources[resource] = pressure
+			}
+			if len(resources) > 0 {
+				result.Cgroups[cgroup] = resources
+			}
+		}
+	}
+
+	c.lastTimestamp = now
+	c.lastTotals = totals
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckPressure) Configure(config *config.Configuration) (bool, error) {
+	c.cgroups = config.PressureCgroups
+	return config.Pressure, nil
+}
@@ -0,0 +1,173 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const pressureCpuFixture = `some avg10=1.91 avg60=2.30 avg300=2.44 total=28184541
+full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+`
+
+const pressureMemoryFixture = `some avg10=0.15 avg60=0.92 avg300=2.20 total=41529196
+full avg10=0.14 avg60=0.77 avg300=1.92 total=37861813
+`
+
+// kernels before 5.13 do not report full for cpu
+const pressureCpuOldKernelFixture = `some avg10=0.00 avg60=0.00 avg300=0.00 total=1200
+`
+
+func TestParsePressure(t *testing.T) {
+	result, err := parsePressure(strings.NewReader(pressureMemoryFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Some.Avg10 != 0.15 || result.Some.Avg60 != 0.92 || result.Some.Avg300 != 2.20 || result.Some.Total != 41529196 {
+		t.Fatal("unexpected some line: ", result.Some)
+	}
+	if result.Full == nil || result.Full.Total != 37861813 {
+		t.Fatal("unexpected full line: ", result.Full)
+	}
+
+	result, err = parsePressure(strings.NewReader(pressureCpuOldKernelFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Full != nil {
+		t.Fatal("expected missing full line")
+	}
+
+	if _, err := parsePressure(strings.NewReader("some avg10\n")); err == nil {
+		t.Fatal("expected error for invalid pressure line")
+	}
+}
+
+func TestChecksCheckPressureFixtures(t *testing.T) {
+	tmpDir := t.TempDir()
+	procPath := filepath.Join(tmpDir, "proc")
+	cgroupPath := filepath.Join(tmpDir, "cgroup")
+
+	writeFixtureFiles(t, procPath, map[string]string{
+		"pressure/cpu":    pressureCpuFixture,
+		"pressure/memory": pressureMemoryFixture,
+	})
+	writeFixtureFiles(t, cgroupPath, map[string]string{
+		"cgroup.controllers":                         "cpu io memory pids\n",
+		"system.slice/nginx.service/cpu.pressure":    pressureCpuOldKernelFixture,
+		"system.slice/nginx.service/memory.pressure": pressureMemoryFixture,
+		"user.slice/cpu.pressure":                    pressureCpuFixture,
+	})
+
+	check := &CheckPressure{
+		cgroups:    []string{"system.slice/*.service"},
+		procPath:   procPath,
+		cgroupPath: cgroupPath,
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultPressure)
+	if !ok {
+		t.Fatal("False type")
+	}
+	if !result.Available {
+		t.Fatal("expected pressure information to be available")
+	}
+	if _, ok := result.System["io"]; ok {
+		t.Fatal("unexpected io pressure")
+	}
+	if _, ok := result.Cgroups["user.slice"]; ok {
+		t.Fatal("unexpected cgroup user.slice")
+	}
+	nginx, ok := result.Cgroups[filepath.Join("system.slice", "nginx.service")]
+	if !ok || nginx["memory"] == nil || nginx["cpu"] == nil {
+		t.Fatal("missing pressure for system.slice/nginx.service")
+	}
+
+	// simulate 1 second of cpu stall within the next check interval
+	check.lastTimestamp = time.Now().Add(-10 * time.Second)
+	writeFixtureFiles(t, procPath, map[string]string{"pressure/cpu": strings.Replace(pressureCpuFixture, "total=28184541", "total=29184541", 1)})
+
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(*resultPressure)
+	cpu := result.System["cpu"].Some
+	if cpu.TotalDelta != 1000000 {
+		t.Fatal("unexpected total delta: ", cpu.TotalDelta)
+	}
+	if cpu.StallPercent < 9.9 || cpu.StallPercent > 10.1 {
+		t.Fatal("unexpected stall percent: ", cpu.StallPercent)
+	}
+
+	// a recreated cgroup starts counting from zero again
+	check.lastTimestamp = time.Now().Add(-10 * time.Second)
+	writeFixtureFiles(t, cgroupPath, map[string]string{"system.slice/nginx.service/memory.pressure": strings.Replace(pressureMemoryFixture, "total=41529196", "total=500000", 1)})
+
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(*resultPressure)
+	memory := result.Cgroups[filepath.Join("system.slice", "nginx.service")]["memory"].Some
+	if memory.TotalDelta != 500000 {
+		t.Fatal("unexpected total delta after reset: ", memory.TotalDelta)
+	}
+	if memory.StallPercent < 4.9 || memory.StallPercent > 5.1 {
+		t.Fatal("unexpected stall percent after reset: ", memory.StallPercent)
+	}
+}
+
+func TestChecksCheckPressureUnavailable(t *testing.T) {
+	check := &CheckPressure{
+		procPath:   t.TempDir(),
+		cgroupPath: t.TempDir(),
+	}
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.(*resultPressure).Available {
+		t.Fatal("expected pressure information to be unavailable")
+	}
+}
+
+func TestPressureUnavailable(t *testing.T) {
+	for _, err := range []error{
+		&os.PathError{Op: "open", Path: "/proc/pressure/cpu", Err: syscall.ENOENT},
+		&os.PathError{Op: "read", Path: "/proc/pressure/cpu", Err: syscall.EOPNOTSUPP},
+		&os.PathError{Op: "read", Path: "/proc/pressure/cpu", Err: syscall.EINVAL},
+		fmt.Errorf("reading failed: %w", syscall.EOPNOTSUPP),
+	} {
+		if !pressureUnavailable(err) {
+			t.Error("expected pressure information to be unavailable for: ", err)
+		}
+	}
+	if pressureUnavailable(&os.PathError{Op: "open", Path: "/proc/pressure/cpu", Err: syscall.EACCES}) {
+		t.Error("permission errors should be reported")
+	}
+}
+
+func TestChecksCheckPressure(t *testing.T) {
+	check := &CheckPressure{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
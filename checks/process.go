@@ -1,6 +1,9 @@
 package checks
 
 import (
+	"context"
+	"time"
+
 	"github.com/it-novum/openitcockpit-agent-go/config"
 )
 
@@ -9,6 +12,25 @@ type CheckProcess struct {
 	// processCacheCmdline for windows checks
 	processCacheCmdline   map[uint64]string
 	processCacheIgnorePid map[uint64]uint64
+
+	// procPath, counters of the last run and username cache for linux checks
+	procPath      string
+	lastCounters  map[uint64]*processCounters
+	lastUptime    float64
+	usernameCache map[string]string
+
+	// snapshot of the last run, will be shared with CheckProcessGroups and CheckProcessTop to avoid gathering all processes twice
+	snapshot       []*resultProcess
+	snapshotTime   time.Time
+	snapshotMaxAge time.Duration
+}
+
+// processCounters is used to calculate the CPU and IO usage between two runs
+type processCounters struct {
+	StartTime  uint64 // start time in clock ticks since boot to detect reused pids
+	Ticks      uint64 // user and system time in clock ticks
+	ReadBytes  uint64
+	WriteBytes uint64
 }
 
 type resultMemoryPosix struct {
@@ -30,20 +52,32 @@ type resultMemoryWindows struct {
 	PrivateBytes      uint64 `json:"private_bytes"`
 }
 
+type resultCtxSwitches struct {
+	Voluntary   uint64 `json:"voluntary"`
+	Involuntary uint64 `json:"involuntary"`
+}
+
 type resultProcess struct {
-	Pid           uint64   `json:"pid"`            // Pid of the process itself
-	Ppid          uint64   `json:"ppid"`           // Pid of the parent process
-	Username      string   `json:"username"`       // Username which runs the process
-	Name          string   `json:"name"`           // (empty on macOS?)
-	CPUPercent    float64  `json:"cpu_percent"`    // Used CPU resources as percentage
-	MemoryPercent float64  `json:"memory_percent"` // Used memory resources as percentage
-	Cmdline       string   `json:"cmdline"`        // command line e.g.: /Applications/Firefox.app/Contents/MacOS/firefox
-	Status        []string `json:"status"`         // https://psutil.readthedocs.io/en/latest/#process-status-constants
-	Exe           string   `json:"exec"`           // e.g: /Applications/Firefox.app/Contents/MacOS/firefox
-	Nice          int64    `json:"nice_level"`     // e.g.: 0
-	NumFds        uint64   `json:"num_fds"`        // Number of open file descriptor
+	Pid           uint64            `json:"pid"`                    // Pid of the process itself
+	Ppid          uint64            `json:"ppid"`                   // Pid of the parent process
+	Username      string            `json:"username"`               // Username which runs the process
+	Name          string            `json:"name"`                   // (empty on macOS?)
+	CPUPercent    float64           `json:"cpu_percent"`            // Used CPU resources as percentage
+	MemoryPercent float64           `json:"memory_percent"`         // Used memory resources as percentage
+	Cmdline       string            `json:"cmdline"`                // command line e.g.: /Applications/Firefox.app/Contents/MacOS/firefox
+	Status        []string          `json:"status"`                 // https://psutil.readthedocs.io/en/latest/#process-status-constants
+	Exe           string            `json:"exec"`                   // e.g: /Applications/Firefox.app/Contents/MacOS/firefox
+	Nice          int64             `json:"nice_level"`             // e.g.: 0
+	NumFds        uint64            `json:"num_fds"`                // Number of open file descriptor
+	NumThreads    uint64            `json:"num_threads"`            // Number of threads (0 if unknown)
+	ReadBytes     uint64            `json:"read_bytes"`             // Bytes read from storage since the process start (Linux only)
+	WriteBytes    uint64            `json:"write_bytes"`            // Bytes written to storage since the process start (Linux only)
+	ReadBytesPS   float64           `json:"read_bytes_per_second"`  // Bytes read from storage per second since the last run (Linux only)
+	WriteBytesPS  float64           `json:"write_bytes_per_second"` // Bytes written to storage per second since the last run (Linux only)
+	NumSockets    uint64            `json:"num_sockets"`            // Number of open sockets (Linux only)
+	CtxSwitches   resultCtxSwitches `json:"ctx_switches"`           // Context switches since the process start (Linux only)
 	Memory        interface{}
-	CreateTime    int64
+	CreateTime    int64 // Start time of the process as unix timestamp
 }
 
 // Name will be used in the response as check name
@@ -51,7 +85,37 @@ func (c *CheckProcess) Name() string {
 	return "processes"
 }
 
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckProcess) Run(ctx context.Context) (interface{}, error) {
+	return c.updateSnapshot(ctx)
+}
+
+func (c *CheckProcess) updateSnapshot(ctx context.Context) ([]*resultProcess, error) {
+	processes, err := c.processes(ctx)
+	if err != nil {
+		c.snapshot = nil
+		return nil, err
+	}
+	c.snapshot = processes
+	c.snapshotTime = time.Now()
+	return processes, nil
+}
+
+// sharedSnapshot returns the processes of the current check interval or gathers them again
+func (c *CheckProcess) sharedSnapshot(ctx context.Context) ([]*resultProcess, error) {
+	if c.snapshot != nil && time.Since(c.snapshotTime) < c.snapshotMaxAge {
+		return c.snapshot, nil
+	}
+	return c.updateSnapshot(ctx)
+}
+
 // Configure the command or return false if the command was disabled
+// The process list can be disabled with processstats-list if only the process groups are required
 func (c *CheckProcess) Configure(config *config.Configuration) (bool, error) {
-	return config.Processes, nil
+	// the snapshot is shared within a single check interval
+	c.snapshotMaxAge = time.Duration(config.CheckInterval) * time.Second / 2
+	return config.Processes && config.ProcessesList, nil
 }
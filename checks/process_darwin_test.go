@@ -0,0 +1,24 @@
+package checks
+
+import "testing"
+
+func TestParseElapsed(t *testing.T) {
+	for elapsed, expected := range map[string]int64{
+		"00:05":       5,
+		"01:02:03":    3723,
+		"2-01:00:00":  176400,
+		"10-00:00:01": 864001,
+	} {
+		seconds, err := parseElapsed(elapsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seconds != expected {
+			t.Errorf("%s: expected %d, got %d", elapsed, expected, seconds)
+		}
+	}
+
+	if _, err := parseElapsed("invalid"); err == nil {
+		t.Error("expected error for invalid elapsed time")
+	}
+}
@@ -0,0 +1,307 @@
+package checks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/it-novum/openitcockpit-agent-go/safemaths"
+)
+
+// linuxClockTicks is USER_HZ which is 100 on all supported architectures
+const linuxClockTicks = 100
+
+// procStat contains the fields of /proc/<pid>/stat
+type procStat struct {
+	Comm       string
+	State      string
+	Ppid       uint64
+	Ticks      uint64 // utime + stime
+	Nice       int64
+	NumThreads uint64
+	StartTime  uint64 // clock ticks since boot
+	VSize      uint64 // bytes
+	RSS        uint64 // pages
+}
+
+// parseProcStat parses /proc/<pid>/stat, the command name can contain spaces and parentheses
+func parseProcStat(content string) (*procStat, error) {
+	start := strings.Index(content, "(")
+	end := strings.LastIndex(content, ")")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("unexpected stat format: %s", content)
+	}
+
+	// fields starting with the state (field 3 of proc(5))
+	fields := strings.Fields(content[end+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("unexpected stat format: %s", content)
+	}
+
+	values := make(map[int]uint64)
+	for _, i := range []int{1, 11, 12, 17, 19, 20, 21} {
+		value, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected stat format: %s", content)
+		}
+		values[i] = value
+	}
+	nice, err := strconv.ParseInt(fields[16], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat format: %s", content)
+	}
+
+	return &procStat{
+		Comm:       content[start+1 : end],
+		State:      fields[0],
+		Ppid:       values[1],
+		Ticks:      values[11] + values[12],
+		Nice:       nice,
+		NumThreads: values[17],
+		StartTime:  values[19],
+		VSize:      values[20],
+		RSS:        values[21],
+	}, nil
+}
+
+// readProcKeyValues reads files like /proc/<pid>/status or /proc/<pid>/io
+// Values are returned without unit (kB)
+func readProcKeyValues(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		values[key] = strings.TrimSuffix(strings.TrimSpace(value), " kB")
+	}
+	return values, nil
+}
+
+func parseProcUint64(values map[string]string, key string) uint64 {
+	value, _ := strconv.ParseUint(values[key], 10, 64)
+	return value
+}
+
+// readBootTime returns btime of /proc/stat as unix timestamp
+func readBootTime(procPath string) (int64, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("could not find btime in %s", filepath.Join(procPath, "stat"))
+}
+
+// readUptime returns the seconds since boot of /proc/uptime
+func readUptime(procPath string) (float64, error) {
+	content, err := os.ReadFile(filepath.Join(procPath, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected uptime format: %s", content)
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// countProcFds counts the open file descriptors and sockets (requires the permissions of the process owner)
+func countProcFds(pidPath string) (fds uint64, sockets uint64) {
+	fdPath := filepath.Join(pidPath, "fd")
+	entries, err := os.ReadDir(fdPath)
+	if err != nil {
+		return 0, 0
+	}
+	for _, entry := range entries {
+		if link, err := os.Readlink(filepath.Join(fdPath, entry.Name())); err == nil && strings.HasPrefix(link, "socket:[") {
+			sockets++
+		}
+	}
+	return uint64(len(entries)), sockets
+}
+
+func (c *CheckProcess) lookupUsername(uid string) string {
+	if c.usernameCache == nil {
+		c.usernameCache = make(map[string]string)
+	}
+	if name, ok := c.usernameCache[uid]; ok {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	c.usernameCache[uid] = name
+	return name
+}
+
+func (c *CheckProcess) readProcess(pidPath string, pid uint64, bootTime int64, uptime, elapsed, totalMemory float64, counters map[uint64]*processCounters) (*resultProcess, error) {
+	content, err := os.ReadFile(filepath.Join(pidPath, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	stat, err := parseProcStat(string(content))
+	if err != nil {
+		return nil, err
+	}
+	status, err := readProcKeyValues(filepath.Join(pidPath, "status"))
+	if err != nil {
+		return nil, err
+	}
+
+	cmdline := ""
+	name := stat.Comm
+	exe := ""
+	if content, err := os.ReadFile(filepath.Join(pidPath, "cmdline")); err == nil {
+		args := strings.Fields(strings.ReplaceAll(string(content), "\x00", " "))
+		if len(args) > 0 {
+			cmdline = strings.Join(args, " ")
+			exe = args[0]
+			name = path.Base(args[0])
+		}
+	}
+	if cmdline == "" {
+		// kernel thread, same as ps
+		cmdline = "[" + stat.Comm + "]"
+		exe = cmdline
+	}
+	if link, err := os.Readlink(filepath.Join(pidPath, "exe")); err == nil {
+		exe = strings.TrimSuffix(link, " (deleted)")
+	}
+
+	username := ""
+	if uids := strings.Fields(status["Uid"]); len(uids) > 0 {
+		username = c.lookupUsername(uids[0])
+	}
+
+	// CPU usage since the last run or the average since the process start on the first run
+	current := &processCounters{StartTime: stat.StartTime, Ticks: stat.Ticks}
+	counters[pid] = current
+	last, ok := c.lastCounters[pid]
+	if !ok || last.StartTime != stat.StartTime || elapsed <= 0 {
+		last = nil
+	}
+	cpuPercent := 0.0
+	if last != nil {
+		cpuPercent = float64(WrapDiffUint64(last.Ticks, stat.Ticks)) / linuxClockTicks / elapsed * 100
+	} else {
+		cpuPercent = safemaths.DivideFloat64(float64(stat.Ticks)/linuxClockTicks, uptime-float64(stat.StartTime)/linuxClockTicks) * 100
+	}
+
+	rss := stat.RSS * uint64(os.Getpagesize())
+	result := &resultProcess{
+		Pid:           pid,
+		Ppid:          stat.Ppid,
+		Username:      username,
+		Name:          name,
+		CPUPercent:    cpuPercent,
+		MemoryPercent: safemaths.DivideFloat64(float64(rss), totalMemory) * 100,
+		Cmdline:       cmdline,
+		Status:        []string{getStatusName(stat.State)},
+		Exe:           exe,
+		Nice:          stat.Nice,
+		NumThreads:    stat.NumThreads,
+		CtxSwitches: resultCtxSwitches{
+			Voluntary:   parseProcUint64(status, "voluntary_ctxt_switches"),
+			Involuntary: parseProcUint64(status, "nonvoluntary_ctxt_switches"),
+		},
+		Memory: &resultMemoryPosix{
+			RSS:    rss,
+			VMS:    stat.VSize,
+			HWM:    parseProcUint64(status, "VmHWM") * 1024,
+			Data:   parseProcUint64(status, "VmData") * 1024,
+			Stack:  parseProcUint64(status, "VmStk") * 1024,
+			Locked: parseProcUint64(status, "VmLck") * 1024,
+			Swap:   parseProcUint64(status, "VmSwap") * 1024,
+		},
+		CreateTime: bootTime + int64(stat.StartTime/linuxClockTicks),
+	}
+
+	result.NumFds, result.NumSockets = countProcFds(pidPath)
+
+	// requires the permissions of the process owner
+	if io, err := readProcKeyValues(filepath.Join(pidPath, "io")); err == nil {
+		result.ReadBytes = parseProcUint64(io, "read_bytes")
+		result.WriteBytes = parseProcUint64(io, "write_bytes")
+		current.ReadBytes = result.ReadBytes
+		current.WriteBytes = result.WriteBytes
+		if last != nil {
+			result.ReadBytesPS = float64(WrapDiffUint64(last.ReadBytes, result.ReadBytes)) / elapsed
+			result.WriteBytesPS = float64(WrapDiffUint64(last.WriteBytes, result.WriteBytes)) / elapsed
+		}
+	}
+
+	return result, nil
+}
+
+func (c *CheckProcess) processes(ctx context.Context) ([]*resultProcess, error) {
+	procPath := c.procPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+
+	bootTime, err := readBootTime(procPath)
+	if err != nil {
+		return nil, err
+	}
+	uptime, err := readUptime(procPath)
+	if err != nil {
+		return nil, err
+	}
+	totalMemory := 0.0
+	if meminfo, err := readProcKeyValues(filepath.Join(procPath, "meminfo")); err == nil {
+		totalMemory = float64(parseProcUint64(meminfo, "MemTotal") * 1024)
+	}
+
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return nil, err
+	}
+
+	elapsed := 0.0
+	if c.lastCounters != nil {
+		elapsed = uptime - c.lastUptime
+	}
+	counters := make(map[uint64]*processCounters, len(c.lastCounters))
+
+	processResults := make([]*resultProcess, 0, len(entries))
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pid, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		result, err := c.readProcess(filepath.Join(procPath, entry.Name()), pid, bootTime, uptime, elapsed, totalMemory, counters)
+		if err != nil {
+			// process probably vanished
+			continue
+		}
+		processResults = append(processResults, result)
+	}
+
+	c.lastCounters = counters
+	c.lastUptime = uptime
+
+	return processResults, nil
+}
@@ -0,0 +1,143 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func procStatFixture(pid, comm string, ppid, utime, stime int) string {
+	return fmt.Sprintf("%s (%s) S %d %s %s 0 -1 4194560 100 0 0 0 %d %d 0 0 20 5 4 0 1000 104857600 256 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n", pid, comm, ppid, pid, pid, utime, stime)
+}
+
+func writeProcFixture(t *testing.T, procPath string, uptime string, utime, readBytes int) {
+	writeFixtureFiles(t, procPath, map[string]string{
+		"stat":         "cpu  1 2 3 4 5 6 7 0 0 0\nbtime 1700000000\nprocesses 100\n",
+		"uptime":       uptime + " 400.00\n",
+		"meminfo":      "MemTotal:        1048576 kB\nMemFree:          524288 kB\n",
+		"42/stat":      procStatFixture("42", "my (app)", 1, utime, 200),
+		"42/status":    "Name:\tmy (app)\nUid:\t0\t0\t0\t0\nVmHWM:\t    2048 kB\nVmData:\t    1024 kB\nVmStk:\t     132 kB\nVmLck:\t       0 kB\nVmSwap:\t      16 kB\nvoluntary_ctxt_switches:\t10\nnonvoluntary_ctxt_switches:\t3\n",
+		"42/cmdline":   "/usr/bin/my-app\x00--flag\x00",
+		"42/io":        fmt.Sprintf("rchar: 100\nwchar: 200\nread_bytes: %d\nwrite_bytes: 8192\ncancelled_write_bytes: 0\n", readBytes),
+		"42/fd/0":      "",
+		"42/fd/1":      "",
+		"42/fd/2":      "",
+		"2/stat":       procStatFixture("2", "kthreadd", 0, 0, 0),
+		"2/status":     "Name:\tkthreadd\nUid:\t0\t0\t0\t0\n",
+		"2/cmdline":    "",
+		"self/comment": "not a process",
+	})
+}
+
+func TestParseProcStat(t *testing.T) {
+	stat, err := parseProcStat(procStatFixture("42", "my (app)", 1, 300, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Comm != "my (app)" || stat.State != "S" || stat.Ppid != 1 || stat.Ticks != 500 || stat.Nice != 5 || stat.NumThreads != 4 || stat.StartTime != 1000 || stat.VSize != 104857600 || stat.RSS != 256 {
+		t.Fatal("unexpected stat: ", stat)
+	}
+
+	if _, err := parseProcStat("42 (app) S 1 2 3"); err == nil {
+		t.Fatal("expected error for invalid format")
+	}
+}
+
+func TestChecksCheckProcessFixtures(t *testing.T) {
+	procPath := t.TempDir()
+	writeProcFixture(t, procPath, "110.00", 300, 4096)
+	if err := os.Symlink("/usr/bin/my-app (deleted)", filepath.Join(procPath, "42", "exe")); err != nil {
+		t.Fatal(err)
+	}
+	for fd, socket := range map[string]string{"3": "socket:[12345]", "4": "socket:[12346]"} {
+		if err := os.Symlink(socket, filepath.Join(procPath, "42", "fd", fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := &CheckProcess{
+		procPath: procPath,
+	}
+	processes, err := check.processes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != 2 {
+		t.Fatal("expected 2 processes, got ", len(processes))
+	}
+
+	var app, kthread *resultProcess
+	for _, process := range processes {
+		switch process.Pid {
+		case 42:
+			app = process
+		case 2:
+			kthread = process
+		}
+	}
+
+	// 5 seconds CPU time in 100 seconds since the process start
+	if app.CPUPercent != 5 || app.Name != "my-app" || app.Cmdline != "/usr/bin/my-app --flag" || app.Exe != "/usr/bin/my-app" || app.Username != "root" {
+		t.Fatal("unexpected process: ", app)
+	}
+	if app.Ppid != 1 || app.Nice != 5 || app.NumThreads != 4 || app.NumFds != 5 || app.NumSockets != 2 || app.CreateTime != 1700000010 || app.Status[0] != "Sleep" {
+		t.Fatal("unexpected process: ", app)
+	}
+	if app.ReadBytes != 4096 || app.WriteBytes != 8192 || app.ReadBytesPS != 0 || app.CtxSwitches.Voluntary != 10 || app.CtxSwitches.Involuntary != 3 {
+		t.Fatal("unexpected io or context switches: ", app)
+	}
+	memory := app.Memory.(*resultMemoryPosix)
+	rss := uint64(256 * os.Getpagesize())
+	if memory.RSS != rss || memory.VMS != 104857600 || memory.HWM != 2048*1024 || memory.Swap != 16*1024 || app.MemoryPercent != float64(rss)/(1048576*1024)*100 {
+		t.Fatal("unexpected memory: ", memory, app.MemoryPercent)
+	}
+	if kthread.Cmdline != "[kthreadd]" || kthread.Name != "kthreadd" || kthread.Ppid != 0 {
+		t.Fatal("unexpected kernel thread: ", kthread)
+	}
+
+	// 10 seconds CPU time and 10 KiB read in 10 seconds since the last run
+	writeProcFixture(t, procPath, "120.00", 1300, 4096+10240)
+	processes, err = check.processes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, process := range processes {
+		if process.Pid == 42 && (process.CPUPercent != 100 || process.ReadBytesPS != 1024 || process.WriteBytesPS != 0) {
+			t.Fatal("unexpected usage since the last run: ", process.CPUPercent, process.ReadBytesPS, process.WriteBytesPS)
+		}
+	}
+}
+
+func TestChecksCheckProcessLinux(t *testing.T) {
+	check := &CheckProcess{}
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	processes, ok := cr.([]*resultProcess)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	var self *resultProcess
+	for _, process := range processes {
+		if process.Pid == uint64(os.Getpid()) {
+			self = process
+		}
+	}
+	if self == nil {
+		t.Fatal("test process not found")
+	}
+	if self.NumThreads == 0 || self.NumFds == 0 || self.CreateTime == 0 {
+		t.Fatal("unexpected values for the test process: ", self)
+	}
+
+	js, err := json.Marshal(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
@@ -31,6 +31,7 @@ type Win32_PerfFormattedData_PerfProc_Process struct {
 	WorkingSetPrivate    uint64
 	PrivateBytes         uint64
 	HandleCount          uint64
+	ThreadCount          uint64
 	PercentProcessorTime uint16
 }
 
@@ -57,7 +58,7 @@ func fetchProcessInfo(pid uint64) (*ProcessInfo, error) {
 	return result, nil
 }
 
-func (c *CheckProcess) Run(_ context.Context) (interface{}, error) {
+func (c *CheckProcess) processes(_ context.Context) ([]*resultProcess, error) {
 	var processList []*Win32_Process
 	var processPerf []*Win32_PerfFormattedData_PerfProc_Process
 
@@ -65,7 +66,7 @@ func (c *CheckProcess) Run(_ context.Context) (interface{}, error) {
 		return nil, errors.Wrap(err, "could not query wmi for process list")
 	}
 
-	if err := wmi.Query("SELECT IDProcess,WorkingSet,WorkingSetPrivate,PrivateBytes,HandleCount,PercentProcessorTime FROM Win32_PerfFormattedData_PerfProc_Process", &processPerf); err != nil {
+	if err := wmi.Query("SELECT IDProcess,WorkingSet,WorkingSetPrivate,PrivateBytes,HandleCount,ThreadCount,PercentProcessorTime FROM Win32_PerfFormattedData_PerfProc_Process", &processPerf); err != nil {
 		return nil, errors.Wrap(err, "could not query wmi for process perfdata list")
 	}
 
@@ -102,6 +103,7 @@ func (c *CheckProcess) Run(_ context.Context) (interface{}, error) {
 			Cmdline:       proc.CommandLine,
 			Exe:           proc.ExecutablePath,
 			NumFds:        perfdata.HandleCount,
+			NumThreads:    perfdata.ThreadCount,
 			Memory: &resultMemoryWindows{
 				WorkingSet:        perfdata.WorkingSet,
 				WorkingSetPrivate: perfdata.WorkingSetPrivate,
@@ -0,0 +1,144 @@
+package checks
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+type processGroupMatcher struct {
+	name    string
+	process *regexp.Regexp
+	exe     *regexp.Regexp
+	cmdline *regexp.Regexp
+	user    *regexp.Regexp
+}
+
+// CheckProcessGroups aggregates the processes of each configured process group
+type CheckProcessGroups struct {
+	// process gathers the processes, the snapshot of the current check interval will be used
+	process *CheckProcess
+	groups  []*processGroupMatcher
+}
+
+// Name will be used in the response as check name
+func (c *CheckProcessGroups) Name() string {
+	return "process_groups"
+}
+
+type resultProcessGroup struct {
+	Count            uint64   `json:"count"`              // Number of matching processes
+	CPUPercent       float64  `json:"cpu_percent"`        // Sum of the used CPU resources as percentage
+	MemoryPercent    float64  `json:"memory_percent"`     // Sum of the used memory resources as percentage
+	RSS              uint64   `json:"rss"`                // Sum of the resident set size in bytes (Windows: working set)
+	NumFds           uint64   `json:"num_fds"`            // Sum of open file descriptors (Windows: handles)
+	NumThreads       uint64   `json:"num_threads"`        // Sum of threads
+	OldestCreateTime int64    `json:"oldest_create_time"` // Start time of the oldest process as unix timestamp (0 = no process)
+	Pids             []uint64 `json:"pids"`
+}
+
+func compileProcessGroupRegex(regex string) (*regexp.Regexp, error) {
+	if regex == "" {
+		return nil, nil
+	}
+	return regexp.Compile(regex)
+}
+
+func (m *processGroupMatcher) matches(process *resultProcess) bool {
+	if m.process != nil && !m.process.MatchString(process.Name) {
+		return false
+	}
+	if m.exe != nil && !m.exe.MatchString(process.Exe) {
+		return false
+	}
+	if m.cmdline != nil && !m.cmdline.MatchString(process.Cmdline) {
+		return false
+	}
+	if m.user != nil && !m.user.MatchString(process.Username) {
+		return false
+	}
+	return true
+}
+
+func processRSS(process *resultProcess) uint64 {
+	switch memory := process.Memory.(type) {
+	case *resultMemoryPosix:
+		return memory.RSS
+	case *resultMemoryWindows:
+		return memory.WorkingSet
+	}
+	return 0
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckProcessGroups) Run(ctx context.Context) (interface{}, error) {
+	results := make(map[string]*resultProcessGroup, len(c.groups))
+	if len(c.groups) == 0 {
+		return results, nil
+	}
+
+	processes, err := c.process.sharedSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range c.groups {
+		result := &resultProcessGroup{
+			Pids: []uint64{},
+		}
+		results[group.name] = result
+
+		for _, process := range processes {
+			if !group.matches(process) {
+				continue
+			}
+			result.Count++
+			result.CPUPercent += process.CPUPercent
+			result.MemoryPercent += process.MemoryPercent
+			result.RSS += processRSS(process)
+			result.NumFds += process.NumFds
+			result.NumThreads += process.NumThreads
+			if process.CreateTime > 0 && (result.OldestCreateTime == 0 || process.CreateTime < result.OldestCreateTime) {
+				result.OldestCreateTime = process.CreateTime
+			}
+			result.Pids = append(result.Pids, process.Pid)
+		}
+	}
+
+	return results, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckProcessGroups) Configure(config *config.Configuration) (bool, error) {
+	if c.process == nil {
+		c.process = &CheckProcess{}
+		if _, err := c.process.Configure(config); err != nil {
+			return false, err
+		}
+	}
+	c.groups = make([]*processGroupMatcher, 0, len(config.ProcessGroupConfiguration))
+	for _, group := range config.ProcessGroupConfiguration {
+		matcher := &processGroupMatcher{
+			name: group.Name,
+		}
+		var err error
+		if matcher.process, err = compileProcessGroupRegex(group.Process); err != nil {
+			return false, err
+		}
+		if matcher.exe, err = compileProcessGroupRegex(group.Exe); err != nil {
+			return false, err
+		}
+		if matcher.cmdline, err = compileProcessGroupRegex(group.Cmdline); err != nil {
+			return false, err
+		}
+		if matcher.user, err = compileProcessGroupRegex(group.User); err != nil {
+			return false, err
+		}
+		c.groups = append(c.groups, matcher)
+	}
+	return config.Processes && len(c.groups) > 0, nil
+}
@@ -0,0 +1,103 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+func TestChecksCheckProcessGroupsSnapshot(t *testing.T) {
+	process := &CheckProcess{
+		snapshotTime:   time.Now(),
+		snapshotMaxAge: time.Minute,
+	}
+	check := &CheckProcessGroups{process: process}
+	ok, err := check.Configure(&config.Configuration{
+		Processes: true,
+		ProcessGroupConfiguration: []*config.ProcessGroup{
+			{Name: "nginx", Process: "^nginx$", User: "^www-data$"},
+			{Name: "java", Cmdline: "-jar app.jar"},
+			{Name: "none", Exe: "^/opt/missing/"},
+		},
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+
+	process.snapshot = []*resultProcess{
+		{Pid: 10, Name: "nginx", Username: "root", CPUPercent: 1, NumFds: 10, CreateTime: 100, Memory: &resultMemoryPosix{RSS: 1000}},
+		{Pid: 11, Name: "nginx", Username: "www-data", CPUPercent: 2.5, NumFds: 20, NumThreads: 1, CreateTime: 200, Memory: &resultMemoryPosix{RSS: 2000}},
+		{Pid: 12, Name: "nginx", Username: "www-data", CPUPercent: 0.5, NumFds: 30, NumThreads: 1, CreateTime: 150, Memory: &resultMemoryPosix{RSS: 3000}},
+		{Pid: 20, Name: "java", Cmdline: "/usr/bin/java -Xmx1g -jar app.jar", NumThreads: 42, Memory: &resultMemoryWindows{WorkingSet: 4000}},
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, ok := cr.(map[string]*resultProcessGroup)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	nginx := results["nginx"]
+	if nginx.Count != 2 || nginx.CPUPercent != 3 || nginx.RSS != 5000 || nginx.NumFds != 50 || nginx.NumThreads != 2 || nginx.OldestCreateTime != 150 {
+		t.Error("unexpected nginx group: ", nginx)
+	}
+	if len(nginx.Pids) != 2 || nginx.Pids[0] != 11 || nginx.Pids[1] != 12 {
+		t.Error("unexpected nginx pids: ", nginx.Pids)
+	}
+
+	java := results["java"]
+	if java.Count != 1 || java.RSS != 4000 || java.NumThreads != 42 || java.OldestCreateTime != 0 {
+		t.Error("unexpected java group: ", java)
+	}
+
+	none := results["none"]
+	if none.Count != 0 || len(none.Pids) != 0 {
+		t.Error("expected empty group: ", none)
+	}
+}
+
+func TestChecksCheckProcessGroups(t *testing.T) {
+	check := &CheckProcessGroups{}
+	ok, err := check.Configure(&config.Configuration{
+		Processes: true,
+		ProcessGroupConfiguration: []*config.ProcessGroup{
+			{Name: "test", Process: `^checks\.test`},
+		},
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, ok := cr.(map[string]*resultProcessGroup)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	found := false
+	for _, pid := range results["test"].Pids {
+		if pid == uint64(os.Getpid()) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected the test process in the process group: ", results["test"])
+	}
+
+	js, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
@@ -0,0 +1,111 @@
+package checks
+
+import (
+	"context"
+	"sort"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+// CheckProcessTop reports the processes with the highest CPU, memory and IO usage
+type CheckProcessTop struct {
+	// process gathers the processes, the snapshot of the current check interval will be used
+	process *CheckProcess
+	top     int
+}
+
+// Name will be used in the response as check name
+func (c *CheckProcessTop) Name() string {
+	return "process_top"
+}
+
+type resultProcessTopEntry struct {
+	Pid           uint64  `json:"pid"`
+	Name          string  `json:"name"`
+	Username      string  `json:"username"`
+	Cmdline       string  `json:"cmdline"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float64 `json:"memory_percent"`
+	RSS           uint64  `json:"rss"` // Resident set size in bytes (Windows: working set)
+	NumSockets    uint64  `json:"num_sockets"`
+	ReadBytesPS   float64 `json:"read_bytes_per_second"`
+	WriteBytesPS  float64 `json:"write_bytes_per_second"`
+}
+
+type resultProcessTop struct {
+	CPU []*resultProcessTopEntry `json:"cpu"` // Highest CPU usage
+	RSS []*resultProcessTopEntry `json:"rss"` // Highest resident set size
+	IO  []*resultProcessTopEntry `json:"io"`  // Highest read and write bytes per second (Linux only)
+}
+
+func newProcessTopEntry(process *resultProcess) *resultProcessTopEntry {
+	return &resultProcessTopEntry{
+		Pid:           process.Pid,
+		Name:          process.Name,
+		Username:      process.Username,
+		Cmdline:       process.Cmdline,
+		CPUPercent:    process.CPUPercent,
+		MemoryPercent: process.MemoryPercent,
+		RSS:           processRSS(process),
+		NumSockets:    process.NumSockets,
+		ReadBytesPS:   process.ReadBytesPS,
+		WriteBytesPS:  process.WriteBytesPS,
+	}
+}
+
+// topProcesses returns the first n entries ordered by value, entries with a value of 0 will be skipped
+func topProcesses(entries []*resultProcessTopEntry, n int, value func(entry *resultProcessTopEntry) float64) []*resultProcessTopEntry {
+	top := make([]*resultProcessTopEntry, 0, n)
+	for _, entry := range entries {
+		if value(entry) > 0 {
+			top = append(top, entry)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		return value(top[i]) > value(top[j])
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckProcessTop) Run(ctx context.Context) (interface{}, error) {
+	processes, err := c.process.sharedSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*resultProcessTopEntry, 0, len(processes))
+	for _, process := range processes {
+		entries = append(entries, newProcessTopEntry(process))
+	}
+
+	return &resultProcessTop{
+		CPU: topProcesses(entries, c.top, func(entry *resultProcessTopEntry) float64 {
+			return entry.CPUPercent
+		}),
+		RSS: topProcesses(entries, c.top, func(entry *resultProcessTopEntry) float64 {
+			return float64(entry.RSS)
+		}),
+		IO: topProcesses(entries, c.top, func(entry *resultProcessTopEntry) float64 {
+			return entry.ReadBytesPS + entry.WriteBytesPS
+		}),
+	}, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckProcessTop) Configure(config *config.Configuration) (bool, error) {
+	if c.process == nil {
+		c.process = &CheckProcess{}
+		if _, err := c.process.Configure(config); err != nil {
+			return false, err
+		}
+	}
+	c.top = int(config.ProcessesTop)
+	return config.Processes && c.top > 0, nil
+}
@@ -0,0 +1,82 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+func TestChecksCheckProcessTopSnapshot(t *testing.T) {
+	process := &CheckProcess{
+		snapshotTime:   time.Now(),
+		snapshotMaxAge: time.Minute,
+	}
+	check := &CheckProcessTop{process: process}
+	ok, err := check.Configure(&config.Configuration{
+		Processes:    true,
+		ProcessesTop: 2,
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+
+	process.snapshot = []*resultProcess{
+		{Pid: 1, Name: "idle", Memory: &resultMemoryPosix{RSS: 100}},
+		{Pid: 2, Name: "compiler", CPUPercent: 180, Memory: &resultMemoryPosix{RSS: 500}},
+		{Pid: 3, Name: "database", CPUPercent: 20, ReadBytesPS: 1000, WriteBytesPS: 5000, Memory: &resultMemoryPosix{RSS: 9000}},
+		{Pid: 4, Name: "backup", CPUPercent: 5, ReadBytesPS: 20000, Memory: &resultMemoryPosix{RSS: 300}},
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultProcessTop)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	if len(result.CPU) != 2 || result.CPU[0].Pid != 2 || result.CPU[1].Pid != 3 {
+		t.Error("unexpected top cpu: ", result.CPU)
+	}
+	if len(result.RSS) != 2 || result.RSS[0].Pid != 3 || result.RSS[1].Pid != 2 || result.RSS[0].RSS != 9000 {
+		t.Error("unexpected top rss: ", result.RSS)
+	}
+	if len(result.IO) != 2 || result.IO[0].Pid != 4 || result.IO[1].Pid != 3 {
+		t.Error("unexpected top io: ", result.IO)
+	}
+
+	js, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
+
+func TestChecksCheckProcessTop(t *testing.T) {
+	check := &CheckProcessTop{}
+	ok, err := check.Configure(&config.Configuration{
+		CheckInterval: 30,
+		Processes:     true,
+		ProcessesTop:  3,
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultProcessTop)
+	if !ok {
+		t.Fatal("False type")
+	}
+	if len(result.RSS) == 0 || len(result.RSS) > 3 {
+		t.Fatal("unexpected number of top rss processes: ", len(result.RSS))
+	}
+}
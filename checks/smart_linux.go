@@ -0,0 +1,320 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+)
+
+// CheckSmart gathers health information of SATA and NVMe drives from smartctl or sysfs
+type CheckSmart struct {
+	sysPath string
+}
+
+// Name will be used in the response as check name
+func (c *CheckSmart) Name() string {
+	return "smart"
+}
+
+type resultSmartAttribute struct {
+	ID         int    `json:"id"`          // e.g.: 5
+	Name       string `json:"name"`        // e.g.: Reallocated_Sector_Ct
+	Value      int    `json:"value"`       // Normalized value
+	Worst      int    `json:"worst"`       // Worst normalized value
+	Threshold  int    `json:"threshold"`   // Failure threshold of the normalized value
+	Raw        int64  `json:"raw"`         // Raw value
+	WhenFailed string `json:"when_failed"` // now, past or empty
+}
+
+type resultSmart struct {
+	Device       string `json:"device"`         // e.g.: /dev/sda, /dev/nvme0
+	Protocol     string `json:"protocol"`       // ata, nvme or scsi
+	Source       string `json:"source"`         // smartctl or sysfs
+	Model        string `json:"model"`          // e.g.: Samsung SSD 970 EVO Plus 1TB
+	Serial       string `json:"serial"`         // Serial number
+	Firmware     string `json:"firmware"`       // Firmware version
+	Passed       bool   `json:"passed"`         // SMART overall-health self-assessment
+	Temperature  int64  `json:"temperature"`    // Current temperature in °C
+	PowerOnHours uint64 `json:"power_on_hours"` // e.g.: 12345
+	Standby      bool   `json:"standby"`        // Drive is in standby mode and was not woken up
+	ExitStatus   int    `json:"exit_status"`    // Exit status (bitmask) of smartctl
+	Error        string `json:"error"`          // e.g.: Permission denied
+
+	// SATA
+	ReallocatedSectors   uint64                  `json:"reallocated_sectors"`   // Attribute 5
+	PendingSectors       uint64                  `json:"pending_sectors"`       // Attribute 197
+	OfflineUncorrectable uint64                  `json:"offline_uncorrectable"` // Attribute 198
+	Attributes           []*resultSmartAttribute `json:"attributes"`
+
+	// NVMe (null if the health information log is not available e.g.: sysfs without smartctl)
+	CriticalWarning         *uint64 `json:"critical_warning"`          // Bitmask, 0 = no warning
+	PercentageUsed          *uint64 `json:"percentage_used"`           // Estimated percentage of the drive life used (can exceed 100)
+	AvailableSpare          *uint64 `json:"available_spare"`           // Remaining spare capacity as percent
+	AvailableSpareThreshold *uint64 `json:"available_spare_threshold"` // Threshold of the available spare capacity as percent
+	MediaErrors             *uint64 `json:"media_errors"`              // Unrecovered data integrity errors (Counter)
+	UnsafeShutdowns         *uint64 `json:"unsafe_shutdowns"`          // (Counter)
+	ErrorLogEntries         *uint64 `json:"error_log_entries"`         // (Counter)
+}
+
+// smartctlOutput contains the relevant fields of smartctl --json
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	Device struct {
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	Devices []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"devices"`
+	ModelName       string `json:"model_name"`
+	SerialNumber    string `json:"serial_number"`
+	FirmwareVersion string `json:"firmware_version"`
+	PowerMode       string `json:"power_mode"`
+	SmartStatus     *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours uint64 `json:"hours"`
+	} `json:"power_on_time"`
+	AtaSmartAttributes struct {
+		Table []struct {
+			ID         int    `json:"id"`
+			Name       string `json:"name"`
+			Value      int    `json:"value"`
+			Worst      int    `json:"worst"`
+			Thresh     int    `json:"thresh"`
+			WhenFailed string `json:"when_failed"`
+			Raw        struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NvmeSmartHealthInformationLog *struct {
+		CriticalWarning         uint64 `json:"critical_warning"`
+		Temperature             int64  `json:"temperature"`
+		AvailableSpare          uint64 `json:"available_spare"`
+		AvailableSpareThreshold uint64 `json:"available_spare_threshold"`
+		PercentageUsed          uint64 `json:"percentage_used"`
+		PowerOnHours            uint64 `json:"power_on_hours"`
+		UnsafeShutdowns         uint64 `json:"unsafe_shutdowns"`
+		MediaErrors             uint64 `json:"media_errors"`
+		NumErrLogEntries        uint64 `json:"num_err_log_entries"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// parseSmartctlScan parses the output of smartctl --scan --json and returns the device name and type
+func parseSmartctlScan(output []byte) ([][2]string, error) {
+	var scan smartctlOutput
+	if err := json.Unmarshal(output, &scan); err != nil {
+		return nil, err
+	}
+	devices := make([][2]string, 0, len(scan.Devices))
+	for _, device := range scan.Devices {
+		devices = append(devices, [2]string{device.Name, device.Type})
+	}
+	return devices, nil
+}
+
+// parseSmartctl parses the output of smartctl --json -a <device>
+func parseSmartctl(output []byte) (*resultSmart, error) {
+	var data smartctlOutput
+	if err := json.Unmarshal(output, &data); err != nil {
+		return nil, err
+	}
+
+	result := &resultSmart{
+		Device:       data.Device.Name,
+		Protocol:     strings.ToLower(data.Device.Protocol),
+		Source:       "smartctl",
+		Model:        data.ModelName,
+		Serial:       data.SerialNumber,
+		Firmware:     data.FirmwareVersion,
+		Temperature:  data.Temperature.Current,
+		PowerOnHours: data.PowerOnTime.Hours,
+		ExitStatus:   data.Smartctl.ExitStatus,
+		Attributes:   []*resultSmartAttribute{},
+	}
+	if data.SmartStatus != nil {
+		result.Passed = data.SmartStatus.Passed
+	}
+	// smartctl -n standby exits with status 2 and does not read any data
+	result.Standby = strings.EqualFold(data.PowerMode, "standby")
+	for _, message := range data.Smartctl.Messages {
+		if message.Severity == "error" {
+			result.Error = message.String
+		}
+		if strings.Contains(strings.ToUpper(message.String), "STANDBY") {
+			result.Standby = true
+		}
+	}
+
+	for _, attr := range data.AtaSmartAttributes.Table {
+		result.Attributes = append(result.Attributes, &resultSmartAttribute{
+			ID:         attr.ID,
+			Name:       attr.Name,
+			Value:      attr.Value,
+			Worst:      attr.Worst,
+			Threshold:  attr.Thresh,
+			Raw:        attr.Raw.Value,
+			WhenFailed: attr.WhenFailed,
+		})
+		if attr.Raw.Value < 0 {
+			continue
+		}
+		switch attr.ID {
+		case 5:
+			result.ReallocatedSectors = uint64(attr.Raw.Value)
+		case 197:
+			result.PendingSectors = uint64(attr.Raw.Value)
+		case 198:
+			result.OfflineUncorrectable = uint64(attr.Raw.Value)
+		}
+	}
+
+	if health := data.NvmeSmartHealthInformationLog; health != nil {
+		result.CriticalWarning = &health.CriticalWarning
+		result.PercentageUsed = &health.PercentageUsed
+		result.AvailableSpare = &health.AvailableSpare
+		result.AvailableSpareThreshold = &health.AvailableSpareThreshold
+		result.MediaErrors = &health.MediaErrors
+		result.UnsafeShutdowns = &health.UnsafeShutdowns
+		result.ErrorLogEntries = &health.NumErrLogEntries
+		if result.Temperature == 0 {
+			result.Temperature = health.Temperature
+		}
+		if result.PowerOnHours == 0 {
+			result.PowerOnHours = health.PowerOnHours
+		}
+	}
+
+	return result, nil
+}
+
+func (c *CheckSmart) runSmartctl(ctx context.Context) (map[string]*resultSmart, error) {
+	scanResult, err := utils.RunCommand(ctx, utils.CommandArgs{
+		Command: "smartctl --scan --json",
+		Timeout: 10 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error while executing 'smartctl --scan': %s", err)
+	}
+	devices, err := parseSmartctlScan([]byte(scanResult.Stdout))
+	if err != nil {
+		return nil, fmt.Errorf("Error while parsing the output of 'smartctl --scan': %s", err)
+	}
+
+	results := make(map[string]*resultSmart)
+	for _, device := range devices {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// -n standby prevents smartctl from spinning up sleeping disks
+		command := fmt.Sprintf("smartctl --json -a -n standby -d %s %s", device[1], device[0])
+		commandResult, _ := utils.RunCommand(ctx, utils.CommandArgs{
+			Command: command,
+			Timeout: 10 * time.Second,
+		})
+
+		// smartctl uses a bitmask as exit status and reports failing disks with an exit status > 0
+		result, err := parseSmartctl([]byte(commandResult.Stdout))
+		if err != nil {
+			result = &resultSmart{
+				Source:     "smartctl",
+				ExitStatus: commandResult.RC,
+				Error:      fmt.Sprintf("Error while executing '%s': %s", command, strings.TrimSpace(commandResult.Stdout)),
+				Attributes: []*resultSmartAttribute{},
+			}
+		}
+		if result.Device == "" {
+			result.Device = device[0]
+		}
+		name := device[0]
+		if strings.Contains(device[1], ",") {
+			// disks behind a raid controller share the same device e.g.: /dev/bus/0 -d megaraid,1
+			name = fmt.Sprintf("%s [%s]", device[0], device[1])
+		}
+		results[name] = result
+	}
+	return results, nil
+}
+
+// readNvmeSysfs reports the basic information of NVMe controllers if smartctl is not installed
+// sysfs does not provide the health information log, so the health attributes stay null
+func (c *CheckSmart) readNvmeSysfs(sysPath string) map[string]*resultSmart {
+	results := make(map[string]*resultSmart)
+	nvmePath := filepath.Join(sysPath, "class", "nvme")
+	entries, err := os.ReadDir(nvmePath)
+	if err != nil {
+		return results
+	}
+
+	for _, entry := range entries {
+		devPath := filepath.Join(nvmePath, entry.Name())
+		result := &resultSmart{
+			Device:     "/dev/" + entry.Name(),
+			Protocol:   "nvme",
+			Source:     "sysfs",
+			Error:      "smartctl not found, health attributes unavailable",
+			Attributes: []*resultSmartAttribute{},
+		}
+		result.Model, _ = readSysfsString(filepath.Join(devPath, "model"))
+		result.Serial, _ = readSysfsString(filepath.Join(devPath, "serial"))
+		result.Firmware, _ = readSysfsString(filepath.Join(devPath, "firmware_rev"))
+
+		// the composite temperature is available at hwmon since Linux 5.5
+		hwmons, _ := filepath.Glob(filepath.Join(devPath, "hwmon*", "temp1_input"))
+		if len(hwmons) == 0 {
+			hwmons, _ = filepath.Glob(filepath.Join(devPath, "device", "hwmon", "hwmon*", "temp1_input"))
+		}
+		if len(hwmons) > 0 {
+			if value, ok := readSysfsString(hwmons[0]); ok {
+				if milliCelsius, err := strconv.ParseInt(value, 10, 64); err == nil {
+					result.Temperature = milliCelsius / 1000
+				}
+			}
+		}
+		results[result.Device] = result
+	}
+	return results
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckSmart) Run(ctx context.Context) (interface{}, error) {
+	sysPath := c.sysPath
+	if sysPath == "" {
+		sysPath = "/sys"
+	}
+
+	if _, err := exec.LookPath("smartctl"); err == nil {
+		return c.runSmartctl(ctx)
+	}
+	return c.readNvmeSysfs(sysPath), nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckSmart) Configure(config *config.Configuration) (bool, error) {
+	return config.Smart, nil
+}
@@ -0,0 +1,130 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readSmartctlFixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "smartctl", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseSmartctlScan(t *testing.T) {
+	devices, err := parseSmartctlScan(readSmartctlFixture(t, "scan.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 3 {
+		t.Fatal("expected 3 devices: ", devices)
+	}
+	if devices[0] != [2]string{"/dev/sda", "sat"} || devices[2] != [2]string{"/dev/bus/0", "megaraid,0"} {
+		t.Fatal("unexpected devices: ", devices)
+	}
+}
+
+func TestParseSmartctlSata(t *testing.T) {
+	result, err := parseSmartctl(readSmartctlFixture(t, "sata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Device != "/dev/sda" || result.Protocol != "ata" || result.Model != "WDC WD40EFRX-68N32N0" || result.Serial != "WD-WCC7K1234567" {
+		t.Fatal("unexpected device information: ", result)
+	}
+	if !result.Passed || result.Standby || result.ExitStatus != 64 || result.Temperature != 36 || result.PowerOnHours != 40213 {
+		t.Fatal("unexpected health information: ", result)
+	}
+	if result.ReallocatedSectors != 8 || result.PendingSectors != 2 || result.OfflineUncorrectable != 1 {
+		t.Fatal("unexpected sector counts: ", result)
+	}
+	if len(result.Attributes) != 6 {
+		t.Fatal("expected 6 attributes: ", len(result.Attributes))
+	}
+	if attr := result.Attributes[1]; attr.ID != 5 || attr.Name != "Reallocated_Sector_Ct" || attr.Value != 199 || attr.Threshold != 140 || attr.Raw != 8 {
+		t.Fatal("unexpected attribute: ", attr)
+	}
+}
+
+func TestParseSmartctlNvme(t *testing.T) {
+	result, err := parseSmartctl(readSmartctlFixture(t, "nvme.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Device != "/dev/nvme0" || result.Protocol != "nvme" || result.Passed || result.Temperature != 47 || result.PowerOnHours != 21034 {
+		t.Fatal("unexpected device information: ", result)
+	}
+	if result.CriticalWarning == nil || result.PercentageUsed == nil || result.AvailableSpare == nil || result.AvailableSpareThreshold == nil {
+		t.Fatal("missing nvme health values: ", result)
+	}
+	if *result.CriticalWarning != 4 || *result.PercentageUsed != 103 || *result.AvailableSpare != 100 || *result.AvailableSpareThreshold != 10 {
+		t.Fatal("unexpected health information: ", result)
+	}
+	if result.MediaErrors == nil || result.UnsafeShutdowns == nil || result.ErrorLogEntries == nil {
+		t.Fatal("missing nvme error counters: ", result)
+	}
+	if *result.MediaErrors != 12 || *result.UnsafeShutdowns != 37 || *result.ErrorLogEntries != 88 {
+		t.Fatal("unexpected error counters: ", result)
+	}
+	if len(result.Attributes) != 0 {
+		t.Fatal("NVMe drives do not have SMART attributes")
+	}
+}
+
+func TestParseSmartctlStandby(t *testing.T) {
+	result, err := parseSmartctl(readSmartctlFixture(t, "standby.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Standby || result.ExitStatus != 2 || result.Error != "" {
+		t.Fatal("unexpected result for a disk in standby mode: ", result)
+	}
+
+	if _, err := parseSmartctl([]byte("smartctl: command not found")); err == nil {
+		t.Fatal("expected error for invalid output")
+	}
+}
+
+func TestChecksCheckSmartSysfs(t *testing.T) {
+	sysPath := t.TempDir()
+	writeFixtureFiles(t, filepath.Join(sysPath, "class", "nvme", "nvme0"), map[string]string{
+		"model":              "Samsung SSD 970 EVO Plus 1TB            \n",
+		"serial":             "S4EWNX0R123456A     \n",
+		"firmware_rev":       "2B2QEXM7\n",
+		"hwmon3/temp1_input": "46850\n",
+	})
+
+	check := &CheckSmart{}
+	results := check.readNvmeSysfs(sysPath)
+	result, ok := results["/dev/nvme0"]
+	if !ok {
+		t.Fatal("nvme0 not found: ", results)
+	}
+	if result.Source != "sysfs" || result.Model != "Samsung SSD 970 EVO Plus 1TB" || result.Serial != "S4EWNX0R123456A" || result.Temperature != 46 {
+		t.Fatal("unexpected values: ", result)
+	}
+	// a missing health information log must not look like a healthy drive
+	if result.CriticalWarning != nil || result.PercentageUsed != nil || result.MediaErrors != nil || result.Error == "" {
+		t.Fatal("expected unavailable health attributes: ", result)
+	}
+}
+
+func TestChecksCheckSmart(t *testing.T) {
+	check := &CheckSmart{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
@@ -0,0 +1,339 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/safemaths"
+)
+
+// CheckSockets gathers statistics about TCP sockets and the TCP stack
+type CheckSockets struct {
+	procPath string
+
+	initialized  bool
+	lastCounters resultTcpCounters
+}
+
+// Name will be used in the response as check name
+func (c *CheckSockets) Name() string {
+	return "sockets"
+}
+
+// tcpStates maps the hex state of /proc/net/tcp to the name of the state
+// https://github.com/torvalds/linux/blob/master/include/net/tcp_states.h
+var tcpStates = map[uint64]string{
+	0x01: "ESTABLISHED",
+	0x02: "SYN_SENT",
+	0x03: "SYN_RECV",
+	0x04: "FIN_WAIT1",
+	0x05: "FIN_WAIT2",
+	0x06: "TIME_WAIT",
+	0x07: "CLOSE",
+	0x08: "CLOSE_WAIT",
+	0x09: "LAST_ACK",
+	0x0A: "LISTEN",
+	0x0B: "CLOSING",
+	0x0C: "NEW_SYN_RECV",
+}
+
+type resultTcpCounters struct {
+	ActiveOpens     uint64 `json:"active_opens"`     // Outgoing connection attempts
+	PassiveOpens    uint64 `json:"passive_opens"`    // Accepted incoming connections
+	AttemptFails    uint64 `json:"attempt_fails"`    // Failed connection attempts
+	EstabResets     uint64 `json:"estab_resets"`     // Resets of established connections
+	InSegs          uint64 `json:"in_segs"`          // Received segments
+	OutSegs         uint64 `json:"out_segs"`         // Sent segments
+	RetransSegs     uint64 `json:"retrans_segs"`     // Retransmitted segments
+	InErrs          uint64 `json:"in_errs"`          // Received segments with errors
+	OutRsts         uint64 `json:"out_rsts"`         // Sent resets
+	ListenOverflows uint64 `json:"listen_overflows"` // Connections dropped because the accept queue of a listening socket was full
+	ListenDrops     uint64 `json:"listen_drops"`     // All connections dropped by listening sockets (includes overflows)
+	Timeouts        uint64 `json:"timeouts"`         // Retransmission timeouts
+}
+
+type resultListenPort struct {
+	Port        uint16            `json:"port"`
+	Listeners   uint64            `json:"listeners"`    // Number of listening sockets (e.g. IPv4 and IPv6)
+	AcceptQueue uint64            `json:"accept_queue"` // Connections waiting to be accepted by the application
+	Connections uint64            `json:"connections"`  // Number of connections to this port
+	States      map[string]uint64 `json:"states"`       // Connections to this port per state e.g.: {"ESTABLISHED": 12, "CLOSE_WAIT": 3}
+}
+
+type resultSockets struct {
+	Timestamp         int64               `json:"timestamp"`          // Timestamp of the last check evaluation
+	Total             uint64              `json:"total"`              // Number of TCP sockets (IPv4 and IPv6)
+	States            map[string]uint64   `json:"states"`             // Number of TCP sockets per state
+	CurrEstab         uint64              `json:"curr_estab"`         // Connections in state ESTABLISHED or CLOSE_WAIT
+	Counters          resultTcpCounters   `json:"counters"`           // (Counter) since boot
+	Delta             resultTcpCounters   `json:"delta"`              // Difference of the counters since the last check evaluation
+	RetransmitPercent float64             `json:"retransmit_percent"` // Retransmitted segments of all sent segments since the last check evaluation
+	ListenPorts       []*resultListenPort `json:"listen_ports"`       // Listening ports with their connections
+}
+
+// tcpSocket is a line of /proc/net/tcp or /proc/net/tcp6
+type tcpSocket struct {
+	LocalIP    net.IP
+	LocalPort  uint16
+	RemoteIP   net.IP
+	RemotePort uint16
+	State      string
+	TxQueue    uint64
+	RxQueue    uint64
+	UID        uint64
+	Inode      uint64
+}
+
+// parseProcNetAddress parses an address of /proc/net/tcp e.g.: 0100007F:0016
+// The IP address is stored as 32 bit words in host byte order (little endian on all supported platforms)
+func parseProcNetAddress(address string) (net.IP, uint16, error) {
+	hexIP, hexPort, found := strings.Cut(address, ":")
+	if !found {
+		return nil, 0, fmt.Errorf("invalid address %s", address)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, err
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %s", address)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip, uint16(port), nil
+}
+
+// parseProcNetTCP parses /proc/net/tcp or /proc/net/tcp6
+func parseProcNetTCP(r io.Reader) ([]*tcpSocket, error) {
+	sockets := []*tcpSocket{}
+	scanner := bufio.NewScanner(r)
+	// skip header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		socket := &tcpSocket{}
+		var err error
+		if socket.LocalIP, socket.LocalPort, err = parseProcNetAddress(fields[1]); err != nil {
+			continue
+		}
+		if socket.RemoteIP, socket.RemotePort, err = parseProcNetAddress(fields[2]); err != nil {
+			continue
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			continue
+		}
+		socket.State = tcpStates[state]
+		if socket.State == "" {
+			socket.State = "UNKNOWN"
+		}
+		if tx, rx, found := strings.Cut(fields[4], ":"); found {
+			socket.TxQueue, _ = strconv.ParseUint(tx, 16, 64)
+			socket.RxQueue, _ = strconv.ParseUint(rx, 16, 64)
+		}
+		socket.UID, _ = strconv.ParseUint(fields[7], 10, 64)
+		socket.Inode, _ = strconv.ParseUint(fields[9], 10, 64)
+		sockets = append(sockets, socket)
+	}
+	return sockets, scanner.Err()
+}
+
+// readProcNetTCP reads the IPv4 and IPv6 TCP sockets, missing files (e.g. IPv6 disabled) are ignored
+func readProcNetTCP(procPath string) ([]*tcpSocket, error) {
+	sockets := []*tcpSocket{}
+	for _, name := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(filepath.Join(procPath, "net", name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		s, err := parseProcNetTCP(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		sockets = append(sockets, s...)
+	}
+	return sockets, nil
+}
+
+// parseProcNetStat parses the header and value line pairs of /proc/net/snmp and /proc/net/netstat
+// e.g.: Tcp: ActiveOpens PassiveOpens ...\nTcp: 544 240 ...
+func parseProcNetStat(r io.Reader) (map[string]map[string]uint64, error) {
+	stats := map[string]map[string]uint64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		header := strings.Fields(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		values := strings.Fields(scanner.Text())
+		if len(header) == 0 || len(header) != len(values) || header[0] != values[0] {
+			continue
+		}
+		prefix := strings.TrimSuffix(header[0], ":")
+		if stats[prefix] == nil {
+			stats[prefix] = map[string]uint64{}
+		}
+		for i := 1; i < len(header); i++ {
+			// some values like MaxConn are -1
+			if value, err := strconv.ParseUint(values[i], 10, 64); err == nil {
+				stats[prefix][header[i]] = value
+			}
+		}
+	}
+	return stats, scanner.Err()
+}
+
+func readProcNetStat(path string) (map[string]map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseProcNetStat(f)
+}
+
+func (t resultTcpCounters) diff(last resultTcpCounters) resultTcpCounters {
+	return resultTcpCounters{
+		ActiveOpens:     WrapDiffUint64(last.ActiveOpens, t.ActiveOpens),
+		PassiveOpens:    WrapDiffUint64(last.PassiveOpens, t.PassiveOpens),
+		AttemptFails:    WrapDiffUint64(last.AttemptFails, t.AttemptFails),
+		EstabResets:     WrapDiffUint64(last.EstabResets, t.EstabResets),
+		InSegs:          WrapDiffUint64(last.InSegs, t.InSegs),
+		OutSegs:         WrapDiffUint64(last.OutSegs, t.OutSegs),
+		RetransSegs:     WrapDiffUint64(last.RetransSegs, t.RetransSegs),
+		InErrs:          WrapDiffUint64(last.InErrs, t.InErrs),
+		OutRsts:         WrapDiffUint64(last.OutRsts, t.OutRsts),
+		ListenOverflows: WrapDiffUint64(last.ListenOverflows, t.ListenOverflows),
+		ListenDrops:     WrapDiffUint64(last.ListenDrops, t.ListenDrops),
+		Timeouts:        WrapDiffUint64(last.Timeouts, t.Timeouts),
+	}
+}
+
+// summarizeSockets counts the sockets per state and the connections per listening port
+func summarizeSockets(sockets []*tcpSocket, result *resultSockets) {
+	ports := map[uint16]*resultListenPort{}
+	for _, socket := range sockets {
+		if socket.State != "LISTEN" {
+			continue
+		}
+		port, ok := ports[socket.LocalPort]
+		if !ok {
+			port = &resultListenPort{
+				Port:   socket.LocalPort,
+				States: map[string]uint64{},
+			}
+			ports[socket.LocalPort] = port
+		}
+		port.Listeners++
+		// for listening sockets rx_queue is the current length of the accept queue
+		port.AcceptQueue += socket.RxQueue
+	}
+
+	for _, socket := range sockets {
+		result.Total++
+		result.States[socket.State]++
+		if socket.State == "LISTEN" {
+			continue
+		}
+		if port, ok := ports[socket.LocalPort]; ok {
+			port.Connections++
+			port.States[socket.State]++
+		}
+	}
+
+	for _, port := range ports {
+		result.ListenPorts = append(result.ListenPorts, port)
+	}
+	sort.Slice(result.ListenPorts, func(i, j int) bool {
+		return result.ListenPorts[i].Port < result.ListenPorts[j].Port
+	})
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckSockets) Run(ctx context.Context) (interface{}, error) {
+	procPath := c.procPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+
+	result := &resultSockets{
+		Timestamp:   time.Now().Unix(),
+		States:      map[string]uint64{},
+		ListenPorts: []*resultListenPort{},
+	}
+	// report all states, so the values do not disappear if there is no socket in a state
+	for _, state := range tcpStates {
+		result.States[state] = 0
+	}
+
+	sockets, err := readProcNetTCP(procPath)
+	if err != nil {
+		return nil, err
+	}
+	summarizeSockets(sockets, result)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	snmp, err := readProcNetStat(filepath.Join(procPath, "net", "snmp"))
+	if err != nil {
+		return nil, err
+	}
+	tcp := snmp["Tcp"]
+	result.CurrEstab = tcp["CurrEstab"]
+	result.Counters = resultTcpCounters{
+		ActiveOpens:  tcp["ActiveOpens"],
+		PassiveOpens: tcp["PassiveOpens"],
+		AttemptFails: tcp["AttemptFails"],
+		EstabResets:  tcp["EstabResets"],
+		InSegs:       tcp["InSegs"],
+		OutSegs:      tcp["OutSegs"],
+		RetransSegs:  tcp["RetransSegs"],
+		InErrs:       tcp["InErrs"],
+		OutRsts:      tcp["OutRsts"],
+	}
+	if netstat, err := readProcNetStat(filepath.Join(procPath, "net", "netstat")); err == nil {
+		tcpExt := netstat["TcpExt"]
+		result.Counters.ListenOverflows = tcpExt["ListenOverflows"]
+		result.Counters.ListenDrops = tcpExt["ListenDrops"]
+		result.Counters.Timeouts = tcpExt["TCPTimeouts"]
+	}
+
+	if c.initialized {
+		result.Delta = result.Counters.diff(c.lastCounters)
+		result.RetransmitPercent = safemaths.DivideFloat64(float64(result.Delta.RetransSegs), float64(result.Delta.OutSegs)) * 100.0
+	}
+	c.lastCounters = result.Counters
+	c.initialized = true
+
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckSockets) Configure(config *config.Configuration) (bool, error) {
+	return config.Sockets, nil
+}
@@ -0,0 +1,171 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const procNetTCPFixture = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000003 00:00000000 00000000     0        0 21034 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   106        0 18123 1 0000000000000000 100 0 0 10 0
+   2: 0A00000A:0050 0B00000A:D431 01 00000000:00000000 00:00000000 00000000    33        0 31337 1 0000000000000000 20 4 30 10 -1
+   3: 0A00000A:0050 0C00000A:D432 08 00000000:00000000 00:00000000 00000000    33        0 31338 1 0000000000000000 20 4 30 10 -1
+   4: 0A00000A:0050 0C00000A:D433 08 00000000:00000000 00:00000000 00000000    33        0 31339 1 0000000000000000 20 4 30 10 -1
+   5: 0A00000A:A2C4 0D00000A:01BB 06 00000000:00000000 03:00000E8F 00000000     0        0 0 3 0000000000000000
+`
+
+const procNetTCP6Fixture = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:0050 00000000000000000000000000000000:0000 0A 00000000:00000001 00:00000000 00000000     0        0 21035 1 0000000000000000 100 0 0 10 0
+   1: 0000000000000000FFFF00000A00000A:0050 0000000000000000FFFF00000E00000A:E0F1 01 00000000:00000000 00:00000000 00000000    33        0 31340 1 0000000000000000 20 4 30 10 -1
+`
+
+const procNetSnmpFixture = `Ip: Forwarding DefaultTTL InReceives
+Ip: 1 64 123456
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 544 240 287 53 4 6696 6742 10 0 313 0
+Udp: InDatagrams NoPorts InErrors OutDatagrams
+Udp: 100 2 0 120
+`
+
+const procNetNetstatFixture = `TcpExt: SyncookiesSent ListenOverflows ListenDrops TCPTimeouts
+TcpExt: 0 5 7 21
+IpExt: InNoRoutes InTruncatedPkts
+IpExt: 0 0
+`
+
+func TestParseProcNetAddress(t *testing.T) {
+	ip, port, err := parseProcNetAddress("0100007F:0016")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.String() != "127.0.0.1" || port != 22 {
+		t.Fatal("unexpected address: ", ip, port)
+	}
+
+	ip, port, err = parseProcNetAddress("0000000000000000FFFF00000100007F:01BB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.String() != "127.0.0.1" || port != 443 {
+		t.Fatal("unexpected IPv4 mapped address: ", ip, port)
+	}
+
+	ip, _, err = parseProcNetAddress("00000000000000000000000001000000:0050")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.String() != "::1" {
+		t.Fatal("unexpected IPv6 address: ", ip)
+	}
+
+	if _, _, err := parseProcNetAddress("0100007F"); err == nil {
+		t.Fatal("expected error for address without port")
+	}
+}
+
+func TestParseProcNetTCP(t *testing.T) {
+	sockets, err := parseProcNetTCP(strings.NewReader(procNetTCPFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sockets) != 6 {
+		t.Fatal("expected 6 sockets: ", len(sockets))
+	}
+	if s := sockets[0]; s.State != "LISTEN" || s.LocalPort != 80 || s.RxQueue != 3 || s.Inode != 21034 {
+		t.Fatal("unexpected listening socket: ", s)
+	}
+	if s := sockets[2]; s.State != "ESTABLISHED" || s.LocalIP.String() != "10.0.0.10" || s.RemoteIP.String() != "10.0.0.11" || s.RemotePort != 54321 || s.UID != 33 {
+		t.Fatal("unexpected established socket: ", s)
+	}
+}
+
+func TestParseProcNetStat(t *testing.T) {
+	stats, err := parseProcNetStat(strings.NewReader(procNetSnmpFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["Tcp"]["RetransSegs"] != 10 || stats["Tcp"]["OutRsts"] != 313 || stats["Udp"]["NoPorts"] != 2 {
+		t.Fatal("unexpected values: ", stats)
+	}
+	if _, ok := stats["Tcp"]["MaxConn"]; ok {
+		t.Fatal("negative values should be skipped")
+	}
+}
+
+func TestChecksCheckSocketsFixtures(t *testing.T) {
+	procPath := t.TempDir()
+	writeFixtureFiles(t, filepath.Join(procPath, "net"), map[string]string{
+		"tcp":     procNetTCPFixture,
+		"tcp6":    procNetTCP6Fixture,
+		"snmp":    procNetSnmpFixture,
+		"netstat": procNetNetstatFixture,
+	})
+
+	check := &CheckSockets{
+		procPath: procPath,
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultSockets)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	if result.Total != 8 || result.States["LISTEN"] != 3 || result.States["ESTABLISHED"] != 2 || result.States["CLOSE_WAIT"] != 2 || result.States["TIME_WAIT"] != 1 {
+		t.Fatal("unexpected states: ", result.Total, result.States)
+	}
+	if result.States["SYN_SENT"] != 0 {
+		t.Fatal("expected all states to be reported")
+	}
+	if len(result.ListenPorts) != 2 {
+		t.Fatal("expected 2 listening ports: ", len(result.ListenPorts))
+	}
+	http := result.ListenPorts[0]
+	if http.Port != 80 || http.Listeners != 2 || http.AcceptQueue != 4 || http.Connections != 4 || http.States["CLOSE_WAIT"] != 2 || http.States["ESTABLISHED"] != 2 {
+		t.Fatal("unexpected values for port 80: ", http)
+	}
+	if result.CurrEstab != 4 || result.Counters.RetransSegs != 10 || result.Counters.ListenOverflows != 5 || result.Counters.ListenDrops != 7 || result.Counters.Timeouts != 21 {
+		t.Fatal("unexpected counters: ", result.Counters)
+	}
+	if result.Delta.RetransSegs != 0 {
+		t.Fatal("expected no delta on the first run")
+	}
+
+	writeFixtureFiles(t, filepath.Join(procPath, "net"), map[string]string{
+		"snmp":    strings.Replace(procNetSnmpFixture, "6742 10 0 313", "7742 30 0 320", 1),
+		"netstat": strings.Replace(procNetNetstatFixture, "0 5 7 21", "0 8 10 21", 1),
+	})
+
+	cr, err = check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = cr.(*resultSockets)
+	if result.Delta.OutSegs != 1000 || result.Delta.RetransSegs != 20 || result.Delta.OutRsts != 7 || result.Delta.ListenOverflows != 3 {
+		t.Fatal("unexpected deltas: ", result.Delta)
+	}
+	if result.RetransmitPercent != 2.0 {
+		t.Fatal("unexpected retransmit percentage: ", result.RetransmitPercent)
+	}
+}
+
+func TestChecksCheckSockets(t *testing.T) {
+	check := &CheckSockets{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
@@ -0,0 +1,292 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/safemaths"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+)
+
+// CheckStoragePools gathers health information about ZFS pools, LVM thin pools and btrfs file systems
+type CheckStoragePools struct {
+	procPath string
+	sysPath  string
+}
+
+// Name will be used in the response as check name
+func (c *CheckStoragePools) Name() string {
+	return "storage_pools"
+}
+
+type resultZfsPool struct {
+	Name          string  `json:"name"`
+	State         string  `json:"state"`         // ONLINE, DEGRADED, FAULTED, OFFLINE, UNAVAIL, REMOVED or SUSPENDED
+	Size          uint64  `json:"size"`          // Size of the pool in byte (requires zpool)
+	Allocated     uint64  `json:"allocated"`     // Allocated space in byte (requires zpool)
+	Free          uint64  `json:"free"`          // Free space in byte (requires zpool)
+	Percent       float64 `json:"percent"`       // Used space as percent (requires zpool)
+	Fragmentation float64 `json:"fragmentation"` // Fragmentation of the free space as percent (requires zpool)
+}
+
+type resultLvmThinPool struct {
+	VolumeGroup     string  `json:"volume_group"`     // e.g.: vg0
+	Name            string  `json:"name"`             // e.g.: pool0
+	Attributes      string  `json:"attributes"`       // e.g.: twi-aotz--
+	Active          bool    `json:"active"`           // Inactive pools do not report any usage
+	Size            uint64  `json:"size"`             // Size of the data volume in byte
+	MetadataSize    uint64  `json:"metadata_size"`    // Size of the metadata volume in byte
+	DataPercent     float64 `json:"data_percent"`     // Used data space as percent
+	MetadataPercent float64 `json:"metadata_percent"` // Used metadata space as percent
+}
+
+type resultBtrfsDevice struct {
+	DevID            string `json:"devid"`
+	Missing          bool   `json:"missing"`
+	WriteErrors      uint64 `json:"write_errors"`      // Errors since the last reset of the counters (Counter)
+	ReadErrors       uint64 `json:"read_errors"`       // Errors since the last reset of the counters (Counter)
+	FlushErrors      uint64 `json:"flush_errors"`      // Errors since the last reset of the counters (Counter)
+	CorruptionErrors uint64 `json:"corruption_errors"` // Checksum errors since the last reset of the counters (Counter)
+	GenerationErrors uint64 `json:"generation_errors"` // Errors since the last reset of the counters (Counter)
+}
+
+type resultBtrfsFilesystem struct {
+	UUID            string               `json:"uuid"`
+	Label           string               `json:"label"`
+	DataTotal       uint64               `json:"data_total"`       // Allocated data chunks in byte
+	DataUsed        uint64               `json:"data_used"`        // Used space of the data chunks in byte
+	DataPercent     float64              `json:"data_percent"`     // Used space of the data chunks as percent
+	MetadataTotal   uint64               `json:"metadata_total"`   // Allocated metadata chunks in byte
+	MetadataUsed    uint64               `json:"metadata_used"`    // Used space of the metadata chunks in byte
+	MetadataPercent float64              `json:"metadata_percent"` // Used space of the metadata chunks as percent
+	Devices         []*resultBtrfsDevice `json:"devices"`
+}
+
+type resultStoragePools struct {
+	Zfs      []*resultZfsPool         `json:"zfs"`
+	ZfsError string                   `json:"zfs_error"` // e.g.: if zpool failed
+	Lvm      []*resultLvmThinPool     `json:"lvm_thin_pools"`
+	LvmError string                   `json:"lvm_error"` // e.g.: if lvs failed (requires root privileges)
+	Btrfs    []*resultBtrfsFilesystem `json:"btrfs"`
+}
+
+// parseStorageFloat parses percentages of lvs and zpool which can contain a decimal comma or "-"
+func parseStorageFloat(value string) float64 {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "%")
+	f, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// parseZpoolList parses the output of: zpool list -Hp -o name,size,alloc,free,frag,cap,health
+func parseZpoolList(output string) map[string]*resultZfsPool {
+	pools := make(map[string]*resultZfsPool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) != 7 {
+			continue
+		}
+		pool := &resultZfsPool{
+			Name:          fields[0],
+			Fragmentation: parseStorageFloat(fields[4]),
+			Percent:       parseStorageFloat(fields[5]),
+			State:         fields[6],
+		}
+		pool.Size, _ = strconv.ParseUint(fields[1], 10, 64)
+		pool.Allocated, _ = strconv.ParseUint(fields[2], 10, 64)
+		pool.Free, _ = strconv.ParseUint(fields[3], 10, 64)
+		pools[pool.Name] = pool
+	}
+	return pools
+}
+
+// parseLvsThinPools parses the output of:
+// lvs --noheadings --nosuffix --units b --separator ; -o vg_name,lv_name,lv_attr,lv_size,lv_metadata_size,data_percent,metadata_percent
+func parseLvsThinPools(output string) []*resultLvmThinPool {
+	pools := []*resultLvmThinPool{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ";")
+		if len(fields) != 7 {
+			// warnings of lvs
+			continue
+		}
+		attr := fields[2]
+		// the first character of the attributes is the volume type, t = thin pool
+		if len(attr) < 5 || attr[0] != 't' {
+			continue
+		}
+		pool := &resultLvmThinPool{
+			VolumeGroup:     fields[0],
+			Name:            fields[1],
+			Attributes:      attr,
+			Active:          attr[4] == 'a',
+			DataPercent:     parseStorageFloat(fields[5]),
+			MetadataPercent: parseStorageFloat(fields[6]),
+		}
+		pool.Size, _ = strconv.ParseUint(strings.TrimSpace(fields[3]), 10, 64)
+		pool.MetadataSize, _ = strconv.ParseUint(strings.TrimSpace(fields[4]), 10, 64)
+		pools = append(pools, pool)
+	}
+	return pools
+}
+
+func (c *CheckStoragePools) zfsPools(ctx context.Context, procPath string, result *resultStoragePools) {
+	pools := make(map[string]*resultZfsPool)
+
+	// every pool has a directory with a state file since ZFS on Linux 0.8
+	kstatPath := filepath.Join(procPath, "spl", "kstat", "zfs")
+	if entries, err := os.ReadDir(kstatPath); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			if state, ok := readSysfsString(filepath.Join(kstatPath, entry.Name(), "state")); ok {
+				pools[entry.Name()] = &resultZfsPool{
+					Name:  entry.Name(),
+					State: state,
+				}
+			}
+		}
+	}
+
+	if _, err := exec.LookPath("zpool"); err == nil {
+		commandResult, err := utils.RunCommand(ctx, utils.CommandArgs{
+			Command: "zpool list -Hp -o name,size,alloc,free,frag,cap,health",
+			Timeout: 10 * time.Second,
+		})
+		if err != nil || commandResult.RC > 0 {
+			result.ZfsError = fmt.Sprintf("Error while executing 'zpool list': %s", strings.TrimSpace(commandResult.Stdout))
+		} else {
+			for name, pool := range parseZpoolList(commandResult.Stdout) {
+				pools[name] = pool
+			}
+		}
+	}
+
+	for _, pool := range pools {
+		result.Zfs = append(result.Zfs, pool)
+	}
+	sort.Slice(result.Zfs, func(i, j int) bool {
+		return result.Zfs[i].Name < result.Zfs[j].Name
+	})
+}
+
+func (c *CheckStoragePools) lvmThinPools(ctx context.Context, result *resultStoragePools) {
+	if _, err := exec.LookPath("lvs"); err != nil {
+		return
+	}
+	commandResult, err := utils.RunCommand(ctx, utils.CommandArgs{
+		Command: "lvs --noheadings --nosuffix --units b --separator ; -o vg_name,lv_name,lv_attr,lv_size,lv_metadata_size,data_percent,metadata_percent",
+		Timeout: 10 * time.Second,
+	})
+	if err != nil || commandResult.RC > 0 {
+		result.LvmError = fmt.Sprintf("Error while executing 'lvs': %s", strings.TrimSpace(commandResult.Stdout))
+		return
+	}
+	result.Lvm = parseLvsThinPools(commandResult.Stdout)
+}
+
+func readBtrfsAllocation(fsPath, chunkType string) (total, used uint64) {
+	if value, ok := readSysfsString(filepath.Join(fsPath, "allocation", chunkType, "total_bytes")); ok {
+		total, _ = strconv.ParseUint(value, 10, 64)
+	}
+	if value, ok := readSysfsString(filepath.Join(fsPath, "allocation", chunkType, "bytes_used")); ok {
+		used, _ = strconv.ParseUint(value, 10, 64)
+	}
+	return total, used
+}
+
+func (c *CheckStoragePools) btrfsFilesystems(sysPath string, result *resultStoragePools) {
+	btrfsPath := filepath.Join(sysPath, "fs", "btrfs")
+	entries, err := os.ReadDir(btrfsPath)
+	if err != nil {
+		// btrfs module not loaded
+		return
+	}
+
+	for _, entry := range entries {
+		// features is not a file system
+		if !entry.IsDir() || entry.Name() == "features" {
+			continue
+		}
+		fsPath := filepath.Join(btrfsPath, entry.Name())
+		fs := &resultBtrfsFilesystem{
+			UUID:    entry.Name(),
+			Devices: []*resultBtrfsDevice{},
+		}
+		fs.Label, _ = readSysfsString(filepath.Join(fsPath, "label"))
+		fs.DataTotal, fs.DataUsed = readBtrfsAllocation(fsPath, "data")
+		fs.DataPercent = safemaths.DivideFloat64(float64(fs.DataUsed), float64(fs.DataTotal)) * 100.0
+		fs.MetadataTotal, fs.MetadataUsed = readBtrfsAllocation(fsPath, "metadata")
+		fs.MetadataPercent = safemaths.DivideFloat64(float64(fs.MetadataUsed), float64(fs.MetadataTotal)) * 100.0
+
+		// devinfo is available since Linux 5.6, error_stats since Linux 5.14
+		devices, _ := os.ReadDir(filepath.Join(fsPath, "devinfo"))
+		for _, device := range devices {
+			devPath := filepath.Join(fsPath, "devinfo", device.Name())
+			dev := &resultBtrfsDevice{
+				DevID: device.Name(),
+			}
+			if missing, ok := readSysfsString(filepath.Join(devPath, "missing")); ok {
+				dev.Missing = missing == "1"
+			}
+			if stats, err := readFlatKeyedFile(filepath.Join(devPath, "error_stats")); err == nil {
+				dev.WriteErrors = stats["write_errs"]
+				dev.ReadErrors = stats["read_errs"]
+				dev.FlushErrors = stats["flush_errs"]
+				dev.CorruptionErrors = stats["corruption_errs"]
+				dev.GenerationErrors = stats["generation_errs"]
+			}
+			fs.Devices = append(fs.Devices, dev)
+		}
+		result.Btrfs = append(result.Btrfs, fs)
+	}
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckStoragePools) Run(ctx context.Context) (interface{}, error) {
+	procPath := c.procPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+	sysPath := c.sysPath
+	if sysPath == "" {
+		sysPath = "/sys"
+	}
+
+	result := &resultStoragePools{
+		Zfs:   []*resultZfsPool{},
+		Lvm:   []*resultLvmThinPool{},
+		Btrfs: []*resultBtrfsFilesystem{},
+	}
+
+	c.zfsPools(ctx, procPath, result)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.lvmThinPools(ctx, result)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.btrfsFilesystems(sysPath, result)
+
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckStoragePools) Configure(config *config.Configuration) (bool, error) {
+	return config.StoragePools, nil
+}
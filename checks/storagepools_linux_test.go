@@ -0,0 +1,131 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+const zpoolListFixture = "rpool\t1992864825344\t1254378864640\t738485960704\t31\t62\tONLINE\n" +
+	"tank\t7971459301376\t7892705853440\t78753447936\t-\t99\tDEGRADED\n"
+
+const lvsFixture = `  WARNING: Failed to connect to lvmetad. Falling back to device scanning.
+  vg0;pool0;twi-aotz--;107374182400;1073741824;45,12;99,87
+  vg0;root;-wi-ao----;21474836480;0;;
+  vg0;thin1;Vwi-aotz--;53687091200;0;80.00;
+  vg1;pool1;twi---tz--;10737418240;8388608;;
+`
+
+func TestParseZpoolList(t *testing.T) {
+	pools := parseZpoolList(zpoolListFixture)
+	if len(pools) != 2 {
+		t.Fatal("expected 2 pools: ", len(pools))
+	}
+	rpool := pools["rpool"]
+	if rpool.State != "ONLINE" || rpool.Size != 1992864825344 || rpool.Allocated != 1254378864640 || rpool.Free != 738485960704 {
+		t.Fatal("unexpected values for rpool: ", rpool)
+	}
+	if rpool.Percent != 62 || rpool.Fragmentation != 31 {
+		t.Fatal("unexpected percentages for rpool: ", rpool)
+	}
+	if tank := pools["tank"]; tank.State != "DEGRADED" || tank.Percent != 99 || tank.Fragmentation != 0 {
+		t.Fatal("unexpected values for tank: ", tank)
+	}
+}
+
+func TestParseLvsThinPools(t *testing.T) {
+	pools := parseLvsThinPools(lvsFixture)
+	if len(pools) != 2 {
+		t.Fatal("expected only the 2 thin pools: ", len(pools))
+	}
+	pool := pools[0]
+	if pool.VolumeGroup != "vg0" || pool.Name != "pool0" || !pool.Active || pool.Size != 107374182400 || pool.MetadataSize != 1073741824 {
+		t.Fatal("unexpected values for pool0: ", pool)
+	}
+	if pool.DataPercent != 45.12 || pool.MetadataPercent != 99.87 {
+		t.Fatal("unexpected usage for pool0: ", pool.DataPercent, pool.MetadataPercent)
+	}
+	if pool := pools[1]; pool.Name != "pool1" || pool.Active || pool.DataPercent != 0 {
+		t.Fatal("unexpected values for the inactive pool1: ", pool)
+	}
+}
+
+func TestChecksCheckStoragePoolsFixtures(t *testing.T) {
+	procPath := t.TempDir()
+	sysPath := t.TempDir()
+
+	writeFixtureFiles(t, procPath, map[string]string{
+		"spl/kstat/zfs/rpool/state": "ONLINE\n",
+		"spl/kstat/zfs/arcstats":    "",
+	})
+
+	fsPath := filepath.Join(sysPath, "fs", "btrfs", "0b2c3c5e-7f0d-4bd5-9a0e-3f1d5b6a3e21")
+	writeFixtureFiles(t, fsPath, map[string]string{
+		"label":                           "data\n",
+		"allocation/data/total_bytes":     "107374182400\n",
+		"allocation/data/bytes_used":      "53687091200\n",
+		"allocation/metadata/total_bytes": "2147483648\n",
+		"allocation/metadata/bytes_used":  "2040109465\n",
+		"devinfo/1/missing":               "0\n",
+		"devinfo/1/error_stats":           "write_errs 0\nread_errs 0\nflush_errs 0\ncorruption_errs 0\ngeneration_errs 0\n",
+		"devinfo/2/missing":               "1\n",
+		"devinfo/2/error_stats":           "write_errs 12\nread_errs 3\nflush_errs 0\ncorruption_errs 7\ngeneration_errs 0\n",
+	})
+	// features is not a file system
+	writeFixtureFiles(t, sysPath, map[string]string{"fs/btrfs/features/supported_checksums": "crc32c\n"})
+
+	check := &CheckStoragePools{
+		procPath: procPath,
+		sysPath:  sysPath,
+	}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := cr.(*resultStoragePools)
+	if !ok {
+		t.Fatal("False type")
+	}
+
+	// zpool may be installed on the test system and report other pools
+	found := false
+	for _, pool := range result.Zfs {
+		if pool.Name == "rpool" && pool.State != "" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("pool rpool not found: ", result.Zfs)
+	}
+
+	if len(result.Btrfs) != 1 {
+		t.Fatal("expected 1 btrfs file system: ", len(result.Btrfs))
+	}
+	fs := result.Btrfs[0]
+	if fs.Label != "data" || fs.DataPercent != 50.0 || fs.MetadataPercent < 94.9 || fs.MetadataPercent > 95.1 {
+		t.Fatal("unexpected values for the btrfs file system: ", fs)
+	}
+	if len(fs.Devices) != 2 || fs.Devices[0].Missing || !fs.Devices[1].Missing {
+		t.Fatal("unexpected btrfs devices: ", fs.Devices)
+	}
+	if dev := fs.Devices[1]; dev.WriteErrors != 12 || dev.ReadErrors != 3 || dev.CorruptionErrors != 7 {
+		t.Fatal("unexpected error counters: ", dev)
+	}
+}
+
+func TestChecksCheckStoragePools(t *testing.T) {
+	check := &CheckStoragePools{}
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
@@ -3,10 +3,14 @@ package checks
 import (
 	"context"
 	"fmt"
+	"math"
+	"path"
+	"strings"
 
 	"github.com/coreos/go-systemd/v22/dbus"
 	systemdutil "github.com/coreos/go-systemd/v22/util"
 	"github.com/it-novum/openitcockpit-agent-go/config"
+	log "github.com/sirupsen/logrus"
 )
 
 // we have to reuse the systemd dbus connection, because the dbus lib uses some sort of reuse stuff
@@ -30,6 +34,8 @@ func getSystemdConn() (*dbus.Conn, error) {
 
 // CheckSystemd gathers information about Systemd services
 type CheckSystemd struct {
+	patterns []string // e.g.: nginx.service,php*-fpm.service
+	types    []string // e.g.: service,timer
 }
 
 // Name will be used in the response as check name
@@ -38,11 +44,82 @@ func (c *CheckSystemd) Name() string {
 }
 
 type resultSystemdServices struct {
-	ActiveState string
-	Description string
-	LoadState   string
-	Name        string
-	SubState    string
+	ActiveState          string
+	Description          string
+	LoadState            string
+	Name                 string
+	SubState             string
+	NRestarts            uint32 // Restarts by systemd (services only)
+	Result               string // e.g.: success, exit-code, timeout (services only)
+	ExecMainStatus       int32  // Exit code of the main process (services only)
+	ActiveEnterTimestamp int64  // Unix timestamp of the last activation (0 = never)
+	MainPID              uint32 // (services only)
+	MemoryCurrent        uint64 // Memory usage in bytes (0 = unknown, services only)
+}
+
+// systemdTimestamp converts a systemd timestamp (usec since the epoch) into a unix timestamp
+func systemdTimestamp(value interface{}) int64 {
+	usec, ok := value.(uint64)
+	if !ok || usec == 0 || usec == math.MaxUint64 {
+		return 0
+	}
+	return int64(usec / 1000000)
+}
+
+// setSystemdUnitProperties sets the details of the unit from the dbus properties
+func setSystemdUnitProperties(result *resultSystemdServices, properties map[string]interface{}) {
+	result.ActiveEnterTimestamp = systemdTimestamp(properties["ActiveEnterTimestamp"])
+	if value, ok := properties["NRestarts"].(uint32); ok {
+		result.NRestarts = value
+	}
+	if value, ok := properties["Result"].(string); ok {
+		result.Result = value
+	}
+	if value, ok := properties["ExecMainStatus"].(int32); ok {
+		result.ExecMainStatus = value
+	}
+	if value, ok := properties["MainPID"].(uint32); ok {
+		result.MainPID = value
+	}
+	if value, ok := properties["MemoryCurrent"].(uint64); ok && value != math.MaxUint64 {
+		result.MemoryCurrent = value
+	}
+}
+
+// matchSystemdUnit returns true if the unit matches one of the patterns and types or if none are configured
+func matchSystemdUnit(name string, patterns, types []string) bool {
+	if len(types) > 0 {
+		found := false
+		for _, unitType := range types {
+			if strings.HasSuffix(name, "."+unitType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// trimConfigList removes empty entries of comma separated configuration values
+func trimConfigList(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
 }
 
 // Run the actual check
@@ -79,6 +156,9 @@ func (c *CheckSystemd) getServiceListViaDbus(ctx context.Context) ([]*resultSyst
 	systemdResults := make([]*resultSystemdServices, 0, len(units))
 
 	for _, unit := range units {
+		if !matchSystemdUnit(unit.Name, c.patterns, c.types) {
+			continue
+		}
 		result := &resultSystemdServices{
 			ActiveState: unit.ActiveState,
 			Description: unit.Description,
@@ -86,6 +166,17 @@ func (c *CheckSystemd) getServiceListViaDbus(ctx context.Context) ([]*resultSyst
 			Name:        unit.Name,
 			SubState:    unit.SubState,
 		}
+
+		properties, err := conn.GetAllPropertiesContext(ctx, unit.Name)
+		if err != nil {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			// unit probably vanished
+			log.Debugln("Systemd: could not get properties of ", unit.Name, ": ", err)
+		} else {
+			setSystemdUnitProperties(result, properties)
+		}
 		systemdResults = append(systemdResults, result)
 	}
 
@@ -94,5 +185,15 @@ func (c *CheckSystemd) getServiceListViaDbus(ctx context.Context) ([]*resultSyst
 
 // Configure the command or return false if the command was disabled
 func (c *CheckSystemd) Configure(config *config.Configuration) (bool, error) {
+	c.patterns = trimConfigList(config.SystemdServicesPatterns)
+	c.types = make([]string, 0, len(config.SystemdServicesTypes))
+	for _, unitType := range trimConfigList(config.SystemdServicesTypes) {
+		c.types = append(c.types, strings.TrimPrefix(unitType, "."))
+	}
+	for _, pattern := range c.patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return false, fmt.Errorf("invalid systemd unit pattern %s: %s", pattern, err)
+		}
+	}
 	return config.SystemdServices, nil
 }
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
 	systemdutil "github.com/coreos/go-systemd/v22/util"
+	"github.com/it-novum/openitcockpit-agent-go/config"
 )
 
 func TestChecksCheckSystemdServices(t *testing.T) {
@@ -70,3 +72,56 @@ func TestGetServiceListFromDbus(t *testing.T) {
 		t.Fatal("Needle not found: " + needle)
 	}
 }
+
+func TestSetSystemdUnitProperties(t *testing.T) {
+	result := &resultSystemdServices{}
+	setSystemdUnitProperties(result, map[string]interface{}{
+		"ActiveEnterTimestamp": uint64(1700000000123456),
+		"NRestarts":            uint32(3),
+		"Result":               "exit-code",
+		"ExecMainStatus":       int32(2),
+		"MainPID":              uint32(812),
+		"MemoryCurrent":        uint64(104857600),
+	})
+	if result.ActiveEnterTimestamp != 1700000000 || result.NRestarts != 3 || result.Result != "exit-code" || result.ExecMainStatus != 2 || result.MainPID != 812 || result.MemoryCurrent != 104857600 {
+		t.Fatal("unexpected unit details: ", result)
+	}
+
+	result = &resultSystemdServices{}
+	setSystemdUnitProperties(result, map[string]interface{}{
+		"ActiveEnterTimestamp": uint64(0),
+		"MemoryCurrent":        uint64(math.MaxUint64),
+	})
+	if result.ActiveEnterTimestamp != 0 || result.MemoryCurrent != 0 {
+		t.Fatal("expected unset values to be 0: ", result)
+	}
+}
+
+func TestMatchSystemdUnit(t *testing.T) {
+	patterns := []string{"nginx.service", "php*-fpm.service", "backup-*"}
+	types := []string{"service", "timer"}
+
+	for name, expected := range map[string]bool{
+		"nginx.service":       true,
+		"php8.2-fpm.service":  true,
+		"backup-daily.timer":  true,
+		"backup-daily.socket": false,
+		"sshd.service":        false,
+	} {
+		if matchSystemdUnit(name, patterns, types) != expected {
+			t.Error("unexpected match result for ", name)
+		}
+	}
+
+	if !matchSystemdUnit("sshd.service", nil, nil) {
+		t.Error("expected all units to match without patterns and types")
+	}
+	if matchSystemdUnit("sshd.service", nil, []string{"timer"}) {
+		t.Error("expected service not to match the timer type")
+	}
+
+	c := &CheckSystemd{}
+	if _, err := c.Configure(&config.Configuration{SystemdServicesPatterns: []string{"[nginx"}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,132 @@
+package checks
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	systemdutil "github.com/coreos/go-systemd/v22/util"
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// CheckSystemdTimers gathers the last and next run of Systemd timers and the result of the triggered unit
+type CheckSystemdTimers struct {
+}
+
+// Name will be used in the response as check name
+func (c *CheckSystemdTimers) Name() string {
+	return "systemd_timers"
+}
+
+type resultSystemdTimer struct {
+	Name           string `json:"name"`             // e.g.: logrotate.timer
+	Unit           string `json:"unit"`             // Triggered unit e.g.: logrotate.service
+	ActiveState    string `json:"active_state"`     // State of the timer
+	LastTrigger    int64  `json:"last_trigger"`     // Unix timestamp (0 = never)
+	NextElapse     int64  `json:"next_elapse"`      // Unix timestamp (0 = no next elapse)
+	LastResult     string `json:"last_result"`      // Result of the triggered unit e.g.: success, exit-code
+	LastExitStatus int32  `json:"last_exit_status"` // Exit code of the main process of the triggered unit
+}
+
+// monotonicNow returns the current value of CLOCK_MONOTONIC in usec
+func monotonicNow() uint64 {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0
+	}
+	return uint64(ts.Nano() / 1000)
+}
+
+// systemdNextElapse returns the next elapse of a timer as unix timestamp
+// Timers like OnBootSec only have a monotonic next elapse (usec since boot)
+func systemdNextElapse(timerProperties map[string]interface{}, now time.Time, monotonic uint64) int64 {
+	if next := systemdTimestamp(timerProperties["NextElapseUSecRealtime"]); next != 0 {
+		return next
+	}
+	usec, ok := timerProperties["NextElapseUSecMonotonic"].(uint64)
+	if !ok || usec == 0 || usec == math.MaxUint64 || monotonic == 0 {
+		return 0
+	}
+	if usec < monotonic {
+		return now.Unix()
+	}
+	return now.Add(time.Duration(usec-monotonic) * time.Microsecond).Unix()
+}
+
+// newSystemdTimer creates the result of a timer from the dbus properties of the timer and the triggered unit
+func newSystemdTimer(name, activeState string, timerProperties, unitProperties map[string]interface{}, now time.Time, monotonic uint64) *resultSystemdTimer {
+	result := &resultSystemdTimer{
+		Name:        name,
+		ActiveState: activeState,
+		LastTrigger: systemdTimestamp(timerProperties["LastTriggerUSec"]),
+		NextElapse:  systemdNextElapse(timerProperties, now, monotonic),
+	}
+	if unit, ok := timerProperties["Unit"].(string); ok {
+		result.Unit = unit
+	}
+	if value, ok := unitProperties["Result"].(string); ok {
+		result.LastResult = value
+	}
+	if value, ok := unitProperties["ExecMainStatus"].(int32); ok {
+		result.LastExitStatus = value
+	}
+	return result
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckSystemdTimers) Run(ctx context.Context) (interface{}, error) {
+	if !systemdutil.IsRunningSystemd() {
+		return []*resultSystemdTimer{}, nil
+	}
+
+	conn, err := getSystemdConn()
+	if err != nil {
+		return nil, err
+	}
+
+	units, err := conn.ListUnitsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	monotonic := monotonicNow()
+	results := make([]*resultSystemdTimer, 0)
+	for _, unit := range units {
+		if !strings.HasSuffix(unit.Name, ".timer") {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		timerProperties, err := conn.GetUnitTypePropertiesContext(ctx, unit.Name, "Timer")
+		if err != nil {
+			// timer probably vanished
+			log.Debugln("Systemd: could not get properties of ", unit.Name, ": ", err)
+			continue
+		}
+		unitProperties := map[string]interface{}{}
+		if triggered, ok := timerProperties["Unit"].(string); ok && triggered != "" {
+			if properties, err := conn.GetAllPropertiesContext(ctx, triggered); err == nil {
+				unitProperties = properties
+			} else {
+				log.Debugln("Systemd: could not get properties of ", triggered, ": ", err)
+			}
+		}
+		results = append(results, newSystemdTimer(unit.Name, unit.ActiveState, timerProperties, unitProperties, now, monotonic))
+	}
+
+	return results, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckSystemdTimers) Configure(config *config.Configuration) (bool, error) {
+	return config.SystemdTimers, nil
+}
@@ -0,0 +1,65 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewSystemdTimer(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	timer := newSystemdTimer("logrotate.timer", "active", map[string]interface{}{
+		"Unit":                    "logrotate.service",
+		"LastTriggerUSec":         uint64(1699990000000000),
+		"NextElapseUSecRealtime":  uint64(1700050000000000),
+		"NextElapseUSecMonotonic": uint64(0),
+	}, map[string]interface{}{
+		"Result":         "exit-code",
+		"ExecMainStatus": int32(1),
+	}, now, 5000000)
+	if timer.Unit != "logrotate.service" || timer.LastTrigger != 1699990000 || timer.NextElapse != 1700050000 || timer.LastResult != "exit-code" || timer.LastExitStatus != 1 {
+		t.Fatal("unexpected timer: ", timer)
+	}
+
+	// OnBootSec timer which never elapsed, next elapse in 60 seconds
+	timer = newSystemdTimer("boot.timer", "active", map[string]interface{}{
+		"Unit":                    "boot.service",
+		"LastTriggerUSec":         uint64(0),
+		"NextElapseUSecRealtime":  uint64(0),
+		"NextElapseUSecMonotonic": uint64(65000000),
+	}, map[string]interface{}{}, now, 5000000)
+	if timer.LastTrigger != 0 || timer.NextElapse != 1700000060 || timer.LastResult != "" {
+		t.Fatal("unexpected monotonic timer: ", timer)
+	}
+
+	timer = newSystemdTimer("inactive.timer", "inactive", map[string]interface{}{
+		"NextElapseUSecRealtime":  uint64(0),
+		"NextElapseUSecMonotonic": uint64(math.MaxUint64),
+	}, map[string]interface{}{}, now, 5000000)
+	if timer.NextElapse != 0 {
+		t.Fatal("expected no next elapse: ", timer)
+	}
+}
+
+func TestChecksCheckSystemdTimers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	c := &CheckSystemdTimers{}
+	r, err := c.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.([]*resultSystemdTimer); !ok {
+		t.Fatal("False type")
+	}
+	js, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(js))
+}
@@ -0,0 +1,384 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+	"github.com/it-novum/openitcockpit-agent-go/utils"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// updatesRefreshTimeout is the maximum runtime of the package manager queries
+const updatesRefreshTimeout = 10 * time.Minute
+
+// CheckUpdates gathers pending package updates and whether a reboot is required
+// The package manager queries are expensive, so they run in the background with their own interval
+type CheckUpdates struct {
+	interval           time.Duration
+	bootPath           string
+	rebootRequiredPath string
+
+	mtx         sync.Mutex
+	refreshing  bool
+	lastRefresh time.Time
+	cache       *updatesCache
+}
+
+// Name will be used in the response as check name
+func (c *CheckUpdates) Name() string {
+	return "updates"
+}
+
+// updatesCache is the result of the last package manager query
+type updatesCache struct {
+	PackageManager  string
+	Updates         uint64
+	SecurityUpdates uint64
+	NeedsRestarting bool
+	Error           string
+}
+
+type resultUpdates struct {
+	PackageManager     string `json:"package_manager"`      // apt, dnf, yum, zypper or empty if no supported package manager was found
+	Updates            uint64 `json:"updates"`              // Number of pending updates
+	SecurityUpdates    uint64 `json:"security_updates"`     // Number of pending security updates
+	LastRefresh        int64  `json:"last_refresh"`         // Unix timestamp of the last package manager query (0 = not queried yet)
+	RebootRequired     bool   `json:"reboot_required"`      // true if any of the following reasons requires a reboot
+	RebootRequiredFile bool   `json:"reboot_required_file"` // /var/run/reboot-required exists (Debian/Ubuntu)
+	NeedsRestarting    bool   `json:"needs_restarting"`     // needs-restarting -r reports a required reboot (RHEL/Fedora)
+	RunningKernel      string `json:"running_kernel"`       // e.g.: 6.1.0-13-amd64
+	NewestKernel       string `json:"newest_kernel"`        // Newest kernel in /boot
+	KernelUpdate       bool   `json:"kernel_update"`        // Newest kernel is not the running kernel
+	Error              string `json:"error"`
+}
+
+// compareVersions compares versions like 6.1.0-13-amd64 by numeric and non numeric parts
+func compareVersions(a, b string) int {
+	for a != "" || b != "" {
+		partA, restA := splitVersionPart(a)
+		partB, restB := splitVersionPart(b)
+		if result := compareVersionPart(partA, partB); result != 0 {
+			return result
+		}
+		a, b = restA, restB
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// splitVersionPart returns the leading number or text of version without separators
+func splitVersionPart(version string) (string, string) {
+	version = strings.TrimLeft(version, ".-_+~")
+	if version == "" {
+		return "", ""
+	}
+	i := 1
+	for i < len(version) && isDigit(version[i]) == isDigit(version[0]) && !strings.ContainsRune(".-_+~", rune(version[i])) {
+		i++
+	}
+	return version[:i], version[i:]
+}
+
+func compareVersionPart(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return -1
+	}
+	if b == "" {
+		return 1
+	}
+	if isDigit(a[0]) && isDigit(b[0]) {
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+	} else if isDigit(a[0]) != isDigit(b[0]) {
+		// numbers are newer than text like rpmvercmp
+		if isDigit(a[0]) {
+			return 1
+		}
+		return -1
+	}
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// newestKernel returns the newest kernel version of /boot/vmlinuz-*
+func newestKernel(bootPath string) string {
+	files, _ := filepath.Glob(filepath.Join(bootPath, "vmlinuz-*"))
+	newest := ""
+	for _, file := range files {
+		version := strings.TrimPrefix(filepath.Base(file), "vmlinuz-")
+		if strings.Contains(version, "rescue") {
+			continue
+		}
+		if newest == "" || compareVersions(version, newest) > 0 {
+			newest = version
+		}
+	}
+	return newest
+}
+
+// parseAptUpgrade counts the packages of apt-get -s upgrade
+// e.g.: Inst libssl3 [3.0.9-1] (3.0.11-1~deb12u1 Debian-Security:12/stable-security [amd64])
+func parseAptUpgrade(output string) (updates uint64, security uint64) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Inst ") {
+			continue
+		}
+		updates++
+		if strings.Contains(strings.ToLower(line), "security") {
+			security++
+		}
+	}
+	return updates, security
+}
+
+// parseCheckUpdate counts the packages of dnf/yum check-update
+// e.g.: openssl-libs.x86_64    1:3.0.7-25.el9_3    baseos
+func parseCheckUpdate(output string) uint64 {
+	var updates uint64
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Obsoleting Packages") || strings.HasPrefix(line, "Security:") {
+			// obsoleted packages are listed again
+			break
+		}
+		if line == "" || line[0] == ' ' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 3 && strings.Contains(fields[0], ".") {
+			updates++
+		}
+	}
+	return updates
+}
+
+// parseZypperXML counts the <update> elements of zypper --xmlout list-updates or list-patches
+func parseZypperXML(output string) (uint64, error) {
+	// zypper may print messages before the xml document
+	start := strings.Index(output, "<?xml")
+	if start < 0 {
+		return 0, fmt.Errorf("invalid zypper output")
+	}
+	var updates uint64
+	decoder := xml.NewDecoder(strings.NewReader(output[start:]))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return updates, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if element, ok := token.(xml.StartElement); ok && element.Name.Local == "update" {
+			updates++
+		}
+	}
+}
+
+func runUpdatesCommand(ctx context.Context, command string) (*utils.CommandResult, error) {
+	result, err := utils.RunCommand(ctx, utils.CommandArgs{
+		Command: command,
+		Timeout: updatesRefreshTimeout,
+	})
+	if err != nil {
+		return result, fmt.Errorf("Error while executing '%s': %s", command, err)
+	}
+	return result, nil
+}
+
+// queryUpdates runs the queries of the first available package manager
+func queryUpdates(ctx context.Context) *updatesCache {
+	cache := &updatesCache{}
+	var err error
+	switch {
+	case commandExists("apt-get"):
+		cache.PackageManager = "apt"
+		err = queryApt(ctx, cache)
+	case commandExists("dnf"):
+		cache.PackageManager = "dnf"
+		err = queryCheckUpdate(ctx, "dnf", cache)
+	case commandExists("yum"):
+		cache.PackageManager = "yum"
+		err = queryCheckUpdate(ctx, "yum", cache)
+	case commandExists("zypper"):
+		cache.PackageManager = "zypper"
+		err = queryZypper(ctx, cache)
+	}
+	if err != nil {
+		cache.Error = err.Error()
+	}
+
+	if commandExists("needs-restarting") {
+		// exit code 1 = reboot required
+		if result, err := runUpdatesCommand(ctx, "needs-restarting -r"); err == nil && result.RC == 1 {
+			cache.NeedsRestarting = true
+		}
+	}
+	return cache
+}
+
+func commandExists(command string) bool {
+	_, err := exec.LookPath(command)
+	return err == nil
+}
+
+func queryApt(ctx context.Context, cache *updatesCache) error {
+	// the package lists have to be updated by the system e.g.: apt-daily.timer
+	command := "apt-get -s -o Debug::NoLocking=true upgrade"
+	result, err := runUpdatesCommand(ctx, command)
+	if err != nil {
+		return err
+	}
+	if result.RC != 0 {
+		return fmt.Errorf("Error while executing '%s': %s", command, strings.TrimSpace(result.Stdout))
+	}
+	cache.Updates, cache.SecurityUpdates = parseAptUpgrade(result.Stdout)
+	return nil
+}
+
+func queryCheckUpdate(ctx context.Context, manager string, cache *updatesCache) error {
+	// exit code 100 = updates available
+	command := manager + " -q check-update"
+	result, err := runUpdatesCommand(ctx, command)
+	if err != nil {
+		return err
+	}
+	if result.RC != 0 && result.RC != 100 {
+		return fmt.Errorf("Error while executing '%s': %s", command, strings.TrimSpace(result.Stdout))
+	}
+	cache.Updates = parseCheckUpdate(result.Stdout)
+
+	command = manager + " -q --security check-update"
+	result, err = runUpdatesCommand(ctx, command)
+	if err != nil {
+		return err
+	}
+	if result.RC != 0 && result.RC != 100 {
+		return fmt.Errorf("Error while executing '%s': %s", command, strings.TrimSpace(result.Stdout))
+	}
+	cache.SecurityUpdates = parseCheckUpdate(result.Stdout)
+	return nil
+}
+
+func queryZypper(ctx context.Context, cache *updatesCache) error {
+	for _, query := range []struct {
+		command string
+		value   *uint64
+	}{
+		{"zypper --non-interactive --xmlout list-updates", &cache.Updates},
+		{"zypper --non-interactive --xmlout list-patches --category security", &cache.SecurityUpdates},
+	} {
+		result, err := runUpdatesCommand(ctx, query.command)
+		if err != nil {
+			return err
+		}
+		count, err := parseZypperXML(result.Stdout)
+		if err != nil {
+			return fmt.Errorf("Error while executing '%s': %s", query.command, strings.TrimSpace(result.Stdout))
+		}
+		*query.value = count
+	}
+	return nil
+}
+
+// refresh queries the package manager and stores the result for the next check runs
+func (c *CheckUpdates) refresh(ctx context.Context) {
+	start := time.Now()
+	cache := queryUpdates(ctx)
+	if cache.Error != "" {
+		log.Errorln("Updates: ", cache.Error)
+	}
+	log.Debugln("Updates: package manager query took ", time.Since(start))
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.cache = cache
+	c.lastRefresh = time.Now()
+	c.refreshing = false
+}
+
+// Run the actual check
+// if error != nil the check result will be nil
+// ctx can be canceled and runs the timeout
+// CheckResult will be serialized after the return and should not change until the next call to Run
+func (c *CheckUpdates) Run(_ context.Context) (interface{}, error) {
+	bootPath := c.bootPath
+	if bootPath == "" {
+		bootPath = "/boot"
+	}
+	rebootRequiredPath := c.rebootRequiredPath
+	if rebootRequiredPath == "" {
+		rebootRequiredPath = "/var/run/reboot-required"
+	}
+
+	result := &resultUpdates{}
+
+	c.mtx.Lock()
+	if !c.refreshing && (c.lastRefresh.IsZero() || time.Since(c.lastRefresh) >= c.interval) {
+		// the refresh must not be canceled by the timeout of the check cycle
+		c.refreshing = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), updatesRefreshTimeout)
+			defer cancel()
+			c.refresh(ctx)
+		}()
+	}
+	if c.cache != nil {
+		result.PackageManager = c.cache.PackageManager
+		result.Updates = c.cache.Updates
+		result.SecurityUpdates = c.cache.SecurityUpdates
+		result.NeedsRestarting = c.cache.NeedsRestarting
+		result.Error = c.cache.Error
+		result.LastRefresh = c.lastRefresh.Unix()
+	}
+	c.mtx.Unlock()
+
+	result.RebootRequiredFile = utils.FileExists(rebootRequiredPath)
+
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err == nil {
+		result.RunningKernel = unix.ByteSliceToString(uname.Release[:])
+	}
+	result.NewestKernel = newestKernel(bootPath)
+	result.KernelUpdate = result.RunningKernel != "" && result.NewestKernel != "" && compareVersions(result.NewestKernel, result.RunningKernel) > 0
+
+	result.RebootRequired = result.RebootRequiredFile || result.NeedsRestarting || result.KernelUpdate
+
+	return result, nil
+}
+
+// Configure the command or return false if the command was disabled
+func (c *CheckUpdates) Configure(config *config.Configuration) (bool, error) {
+	c.interval = time.Duration(config.UpdatesInterval) * time.Second
+	return config.Updates, nil
+}
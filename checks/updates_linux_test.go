@@ -0,0 +1,165 @@
+package checks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/it-novum/openitcockpit-agent-go/config"
+)
+
+var aptUpgradeFixture = `NOTE: This is only a simulation!
+Reading package lists...
+Building dependency tree...
+The following packages will be upgraded:
+  libssl3 openssl tzdata
+3 upgraded, 0 newly installed, 0 to remove and 0 not upgraded.
+Inst libssl3 [3.0.9-1] (3.0.11-1~deb12u1 Debian-Security:12/stable-security [amd64])
+Inst openssl [3.0.9-1] (3.0.11-1~deb12u1 Debian-Security:12/stable-security [amd64])
+Inst tzdata [2023c-5] (2023c-5+deb12u1 Debian:12.2/stable-updates [all])
+Conf libssl3 (3.0.11-1~deb12u1 Debian-Security:12/stable-security [amd64])
+Conf openssl (3.0.11-1~deb12u1 Debian-Security:12/stable-security [amd64])
+Conf tzdata (2023c-5+deb12u1 Debian:12.2/stable-updates [all])
+`
+
+var checkUpdateFixture = `
+kernel.x86_64                      5.14.0-362.8.1.el9_3          baseos
+openssl-libs.x86_64                1:3.0.7-25.el9_3              baseos
+python3-very-long-package-name.noarch
+                                   1.2.3-1.el9                   appstream
+Obsoleting Packages
+grub2-tools.x86_64                 1:2.06-70.el9_3.1             baseos
+    grub2-tools.x86_64             1:2.06-61.el9                 @baseos
+`
+
+var zypperListUpdatesFixture = `Loading repository data...
+<?xml version='1.0'?>
+<stream>
+<message type="info">Reading installed packages...</message>
+<update-status version="0.6">
+<update-list>
+<update kind="package" name="openssl-3" edition="3.0.8-150500.5.14.1" arch="x86_64"><summary>Secure Sockets</summary><source url="http://download.opensuse.org" alias="repo-update"/></update>
+<update kind="package" name="timezone" edition="2023c-150000.75.23.1" arch="x86_64"><summary>Timezone</summary><source url="http://download.opensuse.org" alias="repo-update"/></update>
+</update-list>
+</update-status>
+</stream>
+`
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"6.1.0-13-amd64", "6.1.0-9-amd64", 1},
+		{"6.1.0-9-amd64", "6.1.0-13-amd64", -1},
+		{"6.1.0-13-amd64", "6.1.0-13-amd64", 0},
+		{"5.14.0-362.8.1.el9_3.x86_64", "5.14.0-284.30.1.el9_2.x86_64", 1},
+		{"5.15.0-88-generic", "5.15.0-100-generic", -1},
+		{"6.2.0", "6.2.0-rc1", -1},
+		{"6.10.1", "6.9.12", 1},
+	} {
+		if result := compareVersions(test.a, test.b); result != test.expected {
+			t.Errorf("compareVersions(%s, %s) = %d, expected %d", test.a, test.b, result, test.expected)
+		}
+	}
+}
+
+func TestNewestKernel(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"vmlinuz-6.1.0-9-amd64", "vmlinuz-6.1.0-13-amd64", "vmlinuz-0-rescue-1234", "config-6.1.0-99-amd64"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if kernel := newestKernel(dir); kernel != "6.1.0-13-amd64" {
+		t.Error("unexpected newest kernel: ", kernel)
+	}
+	if kernel := newestKernel(filepath.Join(dir, "missing")); kernel != "" {
+		t.Error("expected no kernel: ", kernel)
+	}
+}
+
+func TestParseUpdates(t *testing.T) {
+	updates, security := parseAptUpgrade(aptUpgradeFixture)
+	if updates != 3 || security != 2 {
+		t.Errorf("unexpected apt updates %d and security updates %d", updates, security)
+	}
+
+	if updates := parseCheckUpdate(checkUpdateFixture); updates != 2 {
+		t.Error("unexpected check-update updates: ", updates)
+	}
+
+	updates, err := parseZypperXML(zypperListUpdatesFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updates != 2 {
+		t.Error("unexpected zypper updates: ", updates)
+	}
+	if _, err := parseZypperXML("System management is locked by the application with pid 1234"); err == nil {
+		t.Error("expected error for invalid zypper output")
+	}
+}
+
+func TestChecksCheckUpdates(t *testing.T) {
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "bin")
+	bootDir := filepath.Join(dir, "boot")
+	for _, path := range []string{binDir, bootDir} {
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := "#!/bin/sh\nprintf '%s\\n' 'Inst libssl3 [3.0.9-1] (3.0.11-1~deb12u1 Debian-Security:12/stable-security [amd64])' 'Inst tzdata [2023c-5] (2023c-5+deb12u1 Debian:12.2/stable-updates [all])'\n"
+	if err := os.WriteFile(filepath.Join(binDir, "apt-get"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// no other package managers
+	t.Setenv("PATH", binDir)
+
+	// a kernel newer than any running kernel
+	if err := os.WriteFile(filepath.Join(bootDir, "vmlinuz-999.0.0-1-amd64"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	rebootRequiredPath := filepath.Join(dir, "reboot-required")
+	if err := os.WriteFile(rebootRequiredPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := &CheckUpdates{
+		bootPath:           bootDir,
+		rebootRequiredPath: rebootRequiredPath,
+	}
+	ok, err := check.Configure(&config.Configuration{
+		Updates:         true,
+		UpdatesInterval: 3600,
+	})
+	if err != nil || !ok {
+		t.Fatal("check should be enabled: ", err)
+	}
+
+	// query synchronously, so Run does not start the background refresh
+	check.refresh(context.Background())
+
+	cr, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := cr.(*resultUpdates)
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if result.PackageManager != "apt" || result.Updates != 2 || result.SecurityUpdates != 1 {
+		t.Errorf("unexpected updates: %+v", result)
+	}
+	if result.LastRefresh == 0 {
+		t.Error("expected last refresh")
+	}
+	if result.RunningKernel == "" || result.NewestKernel != "999.0.0-1-amd64" || !result.KernelUpdate {
+		t.Errorf("unexpected kernel: %+v", result)
+	}
+	if !result.RebootRequiredFile || !result.RebootRequired {
+		t.Errorf("expected required reboot: %+v", result)
+	}
+}
@@ -1,10 +1,16 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/it-novum/openitcockpit-agent-go/basiclog"
@@ -31,6 +37,10 @@ type CustomCheck struct {
 	// if not set the command will be just executed as it is
 	Shell         string `mapstructure:"shell"`
 	PowershellExe string `mapstructure:"powershell_exe"`
+	// MaxAttempts is the number of consecutive non-OK results until the result becomes a hard state
+	MaxAttempts int64 `mapstructure:"max_attempts"`
+	// RetryInterval in seconds is used instead of Interval while the check is in a non-OK soft state
+	RetryInterval int64 `mapstructure:"retry_interval"`
 }
 
 type PushConfiguration struct {
@@ -51,6 +61,103 @@ type PrometheusConfiguration struct {
 	ExportersFilePath string `mapstructure:"exporters"`
 }
 
+// IntegrityConfiguration restricts which executables can be run by custom checks
+type IntegrityConfiguration struct {
+	Enable             bool     `mapstructure:"enabled"`
+	AllowedDirectories []string `mapstructure:"allowed-directories"`
+	HashesFile         string   `mapstructure:"hashes"`
+	PublicKeyFile      string   `mapstructure:"public-key"`
+}
+
+// Equal returns true if both integrity configurations are the same
+func (i *IntegrityConfiguration) Equal(other *IntegrityConfiguration) bool {
+	if i == nil || other == nil {
+		return i == other
+	}
+	if i.Enable != other.Enable || i.HashesFile != other.HashesFile || i.PublicKeyFile != other.PublicKeyFile {
+		return false
+	}
+	if len(i.AllowedDirectories) != len(other.AllowedDirectories) {
+		return false
+	}
+	for n, dir := range i.AllowedDirectories {
+		if strings.TrimSpace(dir) != strings.TrimSpace(other.AllowedDirectories[n]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProbesConfiguration enables synthetic probes defined in the probes file
+type ProbesConfiguration struct {
+	Enable         bool   `mapstructure:"enabled"`
+	ProbesFilePath string `mapstructure:"probes"`
+}
+
+// Probe types
+const (
+	ProbeTypeHTTP = "http"
+	ProbeTypeTCP  = "tcp"
+	ProbeTypeDNS  = "dns"
+)
+
+// Probe is a synthetic check (HTTP request, TCP connect or DNS lookup) executed by the Agent
+type Probe struct {
+	Name     string `mapstructure:"-"`
+	Enabled  bool   `mapstructure:"enabled"`
+	Type     string `mapstructure:"type"` // http, tcp or dns
+	Interval int64  `mapstructure:"interval"`
+	Timeout  int64  `mapstructure:"timeout"`
+	// WarningLatency and CriticalLatency in milliseconds (0 = disabled)
+	WarningLatency  int64 `mapstructure:"warning_latency"`
+	CriticalLatency int64 `mapstructure:"critical_latency"`
+
+	// HTTP
+	URL            string   `mapstructure:"url"`
+	Method         string   `mapstructure:"method"`
+	ExpectedStatus []string `mapstructure:"expected_status"` // e.g.: 200,301
+	BodyRegex      string   `mapstructure:"body_regex"`
+	Insecure       bool     `mapstructure:"insecure"` // Do not verify the TLS certificate
+
+	// TCP
+	Address     string `mapstructure:"address"` // host:port
+	BannerRegex string `mapstructure:"banner_regex"`
+
+	// DNS
+	Query       string `mapstructure:"query"`
+	RecordType  string `mapstructure:"record_type"` // A, AAAA, CNAME, MX, NS or TXT
+	Resolver    string `mapstructure:"resolver"`    // host:port, empty = system resolver
+	AnswerRegex string `mapstructure:"answer_regex"`
+}
+
+// Logfile is a log file (or glob pattern) which will be monitored for lines matching the critical or warning regex
+type Logfile struct {
+	Name     string `mapstructure:"-"`
+	Enabled  bool   `mapstructure:"enabled"`
+	File     string `mapstructure:"file"` // Path or glob pattern e.g.: /var/log/app/*.log
+	Critical string `mapstructure:"critical"`
+	Warning  string `mapstructure:"warning"`
+}
+
+// ProcessGroup aggregates all processes matching every configured regex
+type ProcessGroup struct {
+	Name    string `mapstructure:"-"`
+	Enabled bool   `mapstructure:"enabled"`
+	Process string `mapstructure:"name"`    // Regex for the process name e.g.: ^nginx$
+	Exe     string `mapstructure:"exe"`     // Regex for the executable path
+	Cmdline string `mapstructure:"cmdline"` // Regex for the full command line
+	User    string `mapstructure:"user"`    // Regex for the username (empty on Windows)
+}
+
+// FileStat is a file, directory or glob pattern which will be monitored for existence, size and age
+type FileStat struct {
+	Name      string `mapstructure:"-"`
+	Enabled   bool   `mapstructure:"enabled"`
+	Path      string `mapstructure:"path"`      // Path or glob pattern e.g.: /var/backups/*.tar.gz
+	Recursive bool   `mapstructure:"recursive"` // Include the files of all sub directories
+	MaxDepth  int64  `mapstructure:"max_depth"` // Number of directory levels if recursive (0 = unlimited)
+}
+
 type PrometheusExporter struct {
 	Name     string `mapstructure:"-"`
 	Enabled  bool   `mapstructure:"enabled"`
@@ -89,6 +196,19 @@ type Configuration struct {
 	ConfigUpdate         bool   `mapstructure:"config-update-mode"`
 	CustomchecksFilePath string `mapstructure:"customchecks"`
 
+	// Custom Checks
+
+	// CustomchecksMaxConcurrent limits the number of custom checks running at the same time (0 = unlimited)
+	CustomchecksMaxConcurrent int64 `mapstructure:"customchecks-max-concurrent"`
+	// CustomchecksSplay delays the first execution of every custom check by a deterministic offset within its interval
+	CustomchecksSplay bool `mapstructure:"customchecks-splay"`
+	// CustomchecksStateFile stores the last custom check results across reloads and restarts (empty = disabled)
+	CustomchecksStateFile string `mapstructure:"customchecks-state"`
+	// CustomchecksStaleFactor marks a custom check result as stale if it is older than factor * interval (0 = disabled)
+	CustomchecksStaleFactor int64 `mapstructure:"customchecks-stale-factor"`
+	// CustomchecksStaleUnknown reports stale custom check results as UNKNOWN
+	CustomchecksStaleUnknown bool `mapstructure:"customchecks-stale-unknown"`
+
 	// EnablePPROF for debugging memory leaks with the go tool pprof command
 	EnablePPROF bool `mapstructure:"enable-dev-pprof"`
 
@@ -111,10 +231,25 @@ type Configuration struct {
 	WindowsServices bool  `mapstructure:"winservices"`
 	WindowsEventLog bool  `mapstructure:"wineventlog"`
 	SystemdServices bool  `mapstructure:"systemdservices"`
+	SystemdTimers   bool  `mapstructure:"systemdtimers"`
+	Journald        bool  `mapstructure:"journald"`
+	Updates         bool  `mapstructure:"updates"`
 	LaunchdServices bool  `mapstructure:"launchdservices"`
 	Alfresco        bool  `mapstructure:"alfrescostats"`
 	Libvirt         bool  `mapstructure:"libvirt"`
 	Ntp             bool  `mapstructure:"ntp"`
+	Pressure        bool  `mapstructure:"pressure"`
+	Cgroups         bool  `mapstructure:"cgroups"`
+	KernelEvents    bool  `mapstructure:"kernelevents"`
+	Mdraid          bool  `mapstructure:"mdraid"`
+	StoragePools    bool  `mapstructure:"storagepools"`
+	Smart           bool  `mapstructure:"smart"`
+	Sockets         bool  `mapstructure:"sockets"`
+	ListenPorts     bool  `mapstructure:"listenports"`
+	KernelLimits    bool  `mapstructure:"kernellimits"`
+	Certificates    bool  `mapstructure:"certificates"`
+	Logfiles        bool  `mapstructure:"logfiles"`
+	Files           bool  `mapstructure:"filestats"`
 
 	// Alfresco
 
@@ -133,6 +268,98 @@ type Configuration struct {
 	WindowsEventLogCache  int64    `mapstructure:"wineventlog-cache"`  // JD Version
 	WindowsEventLogMethod string   `mapstructure:"wineventlog-method"` // WMI or PowerShell
 
+	// Disk usage
+
+	// DiskstatsIgnoreDevices devices to ignore e.g.: tmpfs,overlay (logical disk names like C: on Windows)
+	DiskstatsIgnoreDevices []string `mapstructure:"diskstats-ignore-devices"`
+	// DiskstatsIncludeFstypes only report file systems of these types (macOS and Linux only)
+	DiskstatsIncludeFstypes []string `mapstructure:"diskstats-include-fstypes"`
+	// DiskstatsExcludeFstypes do not report file systems of these types e.g.: squashfs,nfs* (macOS and Linux only)
+	DiskstatsExcludeFstypes []string `mapstructure:"diskstats-exclude-fstypes"`
+	// DiskstatsIncludeMountpoints only report these mountpoints (glob patterns)
+	DiskstatsIncludeMountpoints []string `mapstructure:"diskstats-include-mountpoints"`
+	// DiskstatsExcludeMountpoints do not report these mountpoints (glob patterns) e.g.: /snap/*
+	DiskstatsExcludeMountpoints []string `mapstructure:"diskstats-exclude-mountpoints"`
+
+	// Pressure stall information (Linux only)
+
+	// PressureCgroups cgroup v2 glob patterns relative to /sys/fs/cgroup e.g.: system.slice/*.service
+	PressureCgroups []string `mapstructure:"pressure-cgroups"`
+
+	// cgroup v2 resource accounting (Linux only)
+
+	// CgroupsUnits cgroup v2 glob patterns relative to /sys/fs/cgroup e.g.: system.slice/*.service
+	CgroupsUnits []string `mapstructure:"cgroups-units"`
+
+	// OOM killer and kernel events (Linux only)
+
+	// KernelEventsCgroups cgroup v2 glob patterns to report OOM kills for
+	KernelEventsCgroups []string `mapstructure:"kernelevents-cgroups"`
+	// KernelEventsKmsg enables scanning of /dev/kmsg for OOM, hung task and filesystem error messages
+	KernelEventsKmsg bool `mapstructure:"kernelevents-kmsg"`
+	// KernelEventsMessages is the number of last matching kernel messages to report
+	KernelEventsMessages int64 `mapstructure:"kernelevents-messages"`
+
+	// Listening ports (Linux only)
+
+	// ListenPortsExpected ports which should be listening e.g.: tcp/22,tcp/443,udp/53
+	ListenPortsExpected []string `mapstructure:"listenports-expected"`
+
+	// TLS certificate expiry
+
+	// CertificatesFiles PEM or DER encoded certificate files or directories
+	CertificatesFiles []string `mapstructure:"certificates-files"`
+	// CertificatesEndpoints TLS endpoints e.g.: localhost:443,127.0.0.1:8443
+	CertificatesEndpoints []string `mapstructure:"certificates-endpoints"`
+
+	// Log file monitoring
+
+	// LogfilesFilePath of the ini file with the log files to monitor
+	LogfilesFilePath string `mapstructure:"logfiles-config"`
+	// LogfilesStateFile stores the read offsets of the log files across restarts
+	LogfilesStateFile string `mapstructure:"logfiles-state"`
+	// LogfilesLines is the number of last matching lines to report
+	LogfilesLines int64 `mapstructure:"logfiles-lines"`
+
+	// Systemd units (Linux only)
+
+	// SystemdServicesPatterns unit name patterns e.g.: nginx.service,php*-fpm.service
+	SystemdServicesPatterns []string `mapstructure:"systemdservices-patterns"`
+	// SystemdServicesTypes unit types e.g.: service,timer
+	SystemdServicesTypes []string `mapstructure:"systemdservices-types"`
+
+	// Systemd journal (Linux only)
+
+	// JournaldUnits units to read e.g.: nginx.service,backup.service (empty = all units)
+	JournaldUnits []string `mapstructure:"journald-units"`
+	// JournaldPriority is the lowest priority to read (0 = emerg ... 7 = debug)
+	JournaldPriority int64 `mapstructure:"journald-priority"`
+	// JournaldRegex only counts entries with a matching message
+	JournaldRegex string `mapstructure:"journald-regex"`
+	// JournaldMessages is the number of last matching entries to report
+	JournaldMessages int64 `mapstructure:"journald-messages"`
+	// JournaldStateFile stores the journal cursor across restarts
+	JournaldStateFile string `mapstructure:"journald-state"`
+
+	// Pending updates (Linux only)
+
+	// UpdatesInterval in seconds between the package manager queries
+	UpdatesInterval int64 `mapstructure:"updates-interval"`
+
+	// Process groups
+
+	// ProcessesList sends the full process list, disable it if only the process groups are required
+	ProcessesList bool `mapstructure:"processstats-list"`
+	// ProcessesTop is the number of processes with the highest CPU, memory and IO usage to report (0 = disabled)
+	ProcessesTop int64 `mapstructure:"processstats-top"`
+	// ProcessGroupsFilePath of the ini file with the process groups
+	ProcessGroupsFilePath string `mapstructure:"processgroups-config"`
+
+	// File and directory age, size and count
+
+	// FilesFilePath of the ini file with the files and directories to monitor
+	FilesFilePath string `mapstructure:"filestats-config"`
+
 	// Push Mode
 
 	OITC *PushConfiguration `json:"oitc"`
@@ -142,43 +369,91 @@ type Configuration struct {
 
 	CustomCheckConfiguration []*CustomCheck `json:"customchecks_configuration" mapstructure:"-"`
 
+	LogfileConfiguration []*Logfile `json:"logfiles_configuration" mapstructure:"-"`
+
+	ProcessGroupConfiguration []*ProcessGroup `json:"processgroups_configuration" mapstructure:"-"`
+
+	FileStatConfiguration []*FileStat `json:"filestats_configuration" mapstructure:"-"`
+
+	// Custom check integrity verification
+	Integrity *IntegrityConfiguration `json:"integrity"`
+
 	// Prometheus Exporter / Proxy
 	Prometheus                      *PrometheusConfiguration `json:"prometheus"`
 	PrometheusExporterConfiguration []*PrometheusExporter    `json:"prometheus_exporter_configuration" mapstructure:"-"`
+
+	// Synthetic HTTP, TCP and DNS probes
+	Probes             *ProbesConfiguration `json:"probes"`
+	ProbeConfiguration []*Probe             `json:"probe_configuration" mapstructure:"-"`
 }
 
 var defaultValue = map[string]interface{}{
-	"port":                 3333,
-	"interval":             30,
-	"qemustats":            true,
-	"cpustats":             true,
-	"load":                 true,
-	"memory":               true,
-	"processstats":         true,
-	"netstats":             true,
-	"netio":                true,
-	"sensors":              true,
-	"diskstats":            true,
-	"diskio":               true,
-	"swap":                 true,
-	"userstats":            true,
-	"winservices":          true,
-	"wineventlog":          true,
-	"systemdservices":      true,
-	"alfrescostats":        true,
-	"libvirt":              true,
-	"ntp":                  true,
-	"wineventlog-logtypes": "System,Application",
-	"wineventlog-age":      3600,
-	"wineventlog-cache":    3600,
-	"wineventlog-method":   "WMI",
-	"customchecks":         filepath.Join(platformpaths.Get().ConfigPath(), "customchecks.ini"),
-	"tls-security-level":   "lax",
-	"autossl-folder":       platformpaths.Get().ConfigPath(),
-	"autossl-csr-file":     filepath.Join(platformpaths.Get().ConfigPath(), "agent.csr"),
-	"autossl-crt-file":     filepath.Join(platformpaths.Get().ConfigPath(), "agent.crt"),
-	"autossl-key-file":     filepath.Join(platformpaths.Get().ConfigPath(), "agent.key"),
-	"autossl-ca-file":      filepath.Join(platformpaths.Get().ConfigPath(), "server_ca.crt"),
+	"port":                          3333,
+	"interval":                      30,
+	"qemustats":                     true,
+	"cpustats":                      true,
+	"load":                          true,
+	"memory":                        true,
+	"processstats":                  true,
+	"processstats-list":             true,
+	"processstats-top":              5,
+	"processgroups-config":          filepath.Join(platformpaths.Get().ConfigPath(), "processgroups.ini"),
+	"netstats":                      true,
+	"netio":                         true,
+	"sensors":                       true,
+	"diskstats":                     true,
+	"diskio":                        true,
+	"swap":                          true,
+	"userstats":                     true,
+	"winservices":                   true,
+	"wineventlog":                   true,
+	"systemdservices":               true,
+	"systemdservices-patterns":      "",
+	"systemdservices-types":         "",
+	"journald-units":                "",
+	"journald-priority":             4,
+	"journald-regex":                "",
+	"journald-messages":             10,
+	"journald-state":                filepath.Join(platformpaths.Get().ConfigPath(), "journald_cursor"),
+	"updates-interval":              3600,
+	"alfrescostats":                 true,
+	"libvirt":                       true,
+	"ntp":                           true,
+	"diskstats-ignore-devices":      "sysfs,proc,udev,devpts,devfs,tmpfs,securityfs,cgroup,cgroup2,pstore,debugfs,hugetlbfs,systemd-1,mqueue,none,sunrpc,nfsd,nsfs,fusectl,configfs,overlay,shm,tracefs,binfmt_misc",
+	"diskstats-include-fstypes":     "",
+	"diskstats-exclude-fstypes":     "",
+	"diskstats-include-mountpoints": "",
+	"diskstats-exclude-mountpoints": "",
+	"pressure":                      false,
+	"cgroups":                       false,
+	"cgroups-units":                 "system.slice/*.service",
+	"kernelevents":                  false,
+	"kernelevents-cgroups":          "system.slice/*.service",
+	"kernelevents-kmsg":             false,
+	"kernelevents-messages":         10,
+	"listenports-expected":          "",
+	"certificates-files":            "",
+	"certificates-endpoints":        "",
+	"logfiles-config":               filepath.Join(platformpaths.Get().ConfigPath(), "logfiles.ini"),
+	"logfiles-state":                filepath.Join(platformpaths.Get().ConfigPath(), "logfiles_state.json"),
+	"logfiles-lines":                5,
+	"filestats-config":              filepath.Join(platformpaths.Get().ConfigPath(), "filestats.ini"),
+	"wineventlog-logtypes":          "System,Application",
+	"wineventlog-age":               3600,
+	"wineventlog-cache":             3600,
+	"wineventlog-method":            "WMI",
+	"customchecks":                  filepath.Join(platformpaths.Get().ConfigPath(), "customchecks.ini"),
+	"customchecks-max-concurrent":   0,
+	"customchecks-splay":            false,
+	"customchecks-state":            filepath.Join(platformpaths.Get().ConfigPath(), "customchecks_state.json"),
+	"customchecks-stale-factor":     3,
+	"customchecks-stale-unknown":    false,
+	"tls-security-level":            "lax",
+	"autossl-folder":                platformpaths.Get().ConfigPath(),
+	"autossl-csr-file":              filepath.Join(platformpaths.Get().ConfigPath(), "agent.csr"),
+	"autossl-crt-file":              filepath.Join(platformpaths.Get().ConfigPath(), "agent.crt"),
+	"autossl-key-file":              filepath.Join(platformpaths.Get().ConfigPath(), "agent.key"),
+	"autossl-ca-file":               filepath.Join(platformpaths.Get().ConfigPath(), "server_ca.crt"),
 }
 
 var oitcDefaultvalue = map[string]interface{}{
@@ -190,6 +465,15 @@ var prometheusDefaultvalue = map[string]interface{}{
 	"exporters": filepath.Join(platformpaths.Get().ConfigPath(), "prometheus_exporters.ini"),
 }
 
+var integrityDefaultvalue = map[string]interface{}{
+	"enabled": false,
+}
+
+var probesDefaultvalue = map[string]interface{}{
+	"enabled": false,
+	"probes":  filepath.Join(platformpaths.Get().ConfigPath(), "probes.ini"),
+}
+
 func setConfigurationDefaults(v *viper.Viper) {
 	for key, value := range defaultValue {
 		v.SetDefault("default."+key, value)
@@ -202,6 +486,14 @@ func setConfigurationDefaults(v *viper.Viper) {
 	for key, value := range prometheusDefaultvalue {
 		v.SetDefault("prometheus."+key, value)
 	}
+
+	for key, value := range integrityDefaultvalue {
+		v.SetDefault("integrity."+key, value)
+	}
+
+	for key, value := range probesDefaultvalue {
+		v.SetDefault("probes."+key, value)
+	}
 }
 
 func unmarshalConfiguration(v *viper.Viper) (*Configuration, error) {
@@ -240,6 +532,60 @@ func unmarshalConfiguration(v *viper.Viper) (*Configuration, error) {
 		cfg.CustomCheckConfiguration = []*CustomCheck{}
 	}
 
+	if cfg.Logfiles && cfg.LogfilesFilePath != "" {
+		if utils.FileExists(cfg.LogfilesFilePath) {
+			if logfiles, err := unmarshalLogfiles(cfg.LogfilesFilePath); err != nil {
+				logger, _ := basiclog.New()
+				logger.Errorln("Configuration: could not load log files: ", err)
+			} else {
+				cfg.LogfileConfiguration = logfiles
+			}
+		} else {
+			logger, _ := basiclog.New()
+			logger.Errorln("Configuration: log file configuration does not exist: ", cfg.LogfilesFilePath)
+		}
+	}
+
+	// we have to set at least an empty array if we don't load any log file configuration
+	if cfg.LogfileConfiguration == nil {
+		cfg.LogfileConfiguration = []*Logfile{}
+	}
+
+	if cfg.Processes && cfg.ProcessGroupsFilePath != "" {
+		if utils.FileExists(cfg.ProcessGroupsFilePath) {
+			if groups, err := unmarshalProcessGroups(cfg.ProcessGroupsFilePath); err != nil {
+				logger, _ := basiclog.New()
+				logger.Errorln("Configuration: could not load process groups: ", err)
+			} else {
+				cfg.ProcessGroupConfiguration = groups
+			}
+		}
+	}
+
+	// we have to set at least an empty array if we don't load any process group configuration
+	if cfg.ProcessGroupConfiguration == nil {
+		cfg.ProcessGroupConfiguration = []*ProcessGroup{}
+	}
+
+	if cfg.Files && cfg.FilesFilePath != "" {
+		if utils.FileExists(cfg.FilesFilePath) {
+			if files, err := unmarshalFileStats(cfg.FilesFilePath); err != nil {
+				logger, _ := basiclog.New()
+				logger.Errorln("Configuration: could not load file stats: ", err)
+			} else {
+				cfg.FileStatConfiguration = files
+			}
+		} else {
+			logger, _ := basiclog.New()
+			logger.Errorln("Configuration: file stats configuration does not exist: ", cfg.FilesFilePath)
+		}
+	}
+
+	// we have to set at least an empty array if we don't load any file stats configuration
+	if cfg.FileStatConfiguration == nil {
+		cfg.FileStatConfiguration = []*FileStat{}
+	}
+
 	// Parse Prometheus Exporter configuration
 	if cfg.Prometheus.ExportersFilePath != "" && cfg.Prometheus.Enable {
 		if utils.FileExists(cfg.Prometheus.ExportersFilePath) {
@@ -260,6 +606,26 @@ func unmarshalConfiguration(v *viper.Viper) (*Configuration, error) {
 		cfg.PrometheusExporterConfiguration = []*PrometheusExporter{}
 	}
 
+	// Parse probe configuration
+	if cfg.Probes.ProbesFilePath != "" && cfg.Probes.Enable {
+		if utils.FileExists(cfg.Probes.ProbesFilePath) {
+			if probes, err := unmarshalProbes(cfg.Probes.ProbesFilePath); err != nil {
+				logger, _ := basiclog.New()
+				logger.Errorln("Configuration: could not load probes: ", err)
+			} else {
+				cfg.ProbeConfiguration = probes
+			}
+		} else {
+			logger, _ := basiclog.New()
+			logger.Errorln("Configuration: probe configuration does not exist: ", cfg.Probes.ProbesFilePath)
+		}
+	}
+
+	// we have to set at least an empty array if we don't load any probe configuration
+	if cfg.ProbeConfiguration == nil {
+		cfg.ProbeConfiguration = []*Probe{}
+	}
+
 	return cfg, nil
 }
 
@@ -279,6 +645,23 @@ func Load(ctx context.Context, configPath string) (*Configuration, error) {
 	return unmarshalConfiguration(v)
 }
 
+// ParseIntegrityConfiguration reads only the integrity section of the given configuration file content
+func ParseIntegrityConfiguration(data []byte) (*IntegrityConfiguration, error) {
+	v := viper.New()
+	setConfigurationDefaults(v)
+	v.SetConfigType("ini")
+
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	integrity := &IntegrityConfiguration{}
+	if err := v.UnmarshalKey("integrity", integrity); err != nil {
+		return nil, err
+	}
+	return integrity, nil
+}
+
 func unmarshalCustomChecks(configPath string) ([]*CustomCheck, error) {
 	v := viper.New()
 	v.SetConfigFile(configPath)
@@ -304,6 +687,12 @@ func unmarshalCustomChecks(configPath string) ([]*CustomCheck, error) {
 			if check.Timeout <= 0 {
 				check.Timeout = 15
 			}
+			if check.MaxAttempts <= 0 {
+				check.MaxAttempts = 1
+			}
+			if check.RetryInterval <= 0 {
+				check.RetryInterval = check.Interval
+			}
 			if strings.TrimSpace(check.Command) == "" {
 				return nil, fmt.Errorf("missing command in custom check: %s", check.Name)
 			}
@@ -316,6 +705,122 @@ func unmarshalCustomChecks(configPath string) ([]*CustomCheck, error) {
 	return checks, nil
 }
 
+func unmarshalLogfiles(configPath string) ([]*Logfile, error) {
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	v.SetConfigType("ini")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	cfg := map[string]*Logfile{}
+
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+
+	logfiles := make([]*Logfile, 0)
+	for name, logfile := range cfg {
+		if name != "default" {
+			logfile.Name = name
+			logfile.File = strings.TrimSpace(logfile.File)
+			if logfile.File == "" {
+				return nil, fmt.Errorf("missing file in log file: %s", logfile.Name)
+			}
+			if logfile.Critical == "" && logfile.Warning == "" {
+				return nil, fmt.Errorf("missing critical or warning regex in log file: %s", logfile.Name)
+			}
+			for _, regex := range []string{logfile.Critical, logfile.Warning} {
+				if _, err := regexp.Compile(regex); err != nil {
+					return nil, fmt.Errorf("invalid regex in log file %s: %s", logfile.Name, err)
+				}
+			}
+			if logfile.Enabled {
+				logfiles = append(logfiles, logfile)
+			}
+		}
+	}
+
+	return logfiles, nil
+}
+
+func unmarshalProcessGroups(configPath string) ([]*ProcessGroup, error) {
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	v.SetConfigType("ini")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	cfg := map[string]*ProcessGroup{}
+
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+
+	groups := make([]*ProcessGroup, 0)
+	for name, group := range cfg {
+		if name != "default" {
+			group.Name = name
+			if group.Process == "" && group.Exe == "" && group.Cmdline == "" && group.User == "" {
+				return nil, fmt.Errorf("missing name, exe, cmdline or user regex in process group: %s", group.Name)
+			}
+			for _, regex := range []string{group.Process, group.Exe, group.Cmdline, group.User} {
+				if _, err := regexp.Compile(regex); err != nil {
+					return nil, fmt.Errorf("invalid regex in process group %s: %s", group.Name, err)
+				}
+			}
+			if group.Enabled {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+func unmarshalFileStats(configPath string) ([]*FileStat, error) {
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	v.SetConfigType("ini")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	cfg := map[string]*FileStat{}
+
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+
+	files := make([]*FileStat, 0)
+	for name, file := range cfg {
+		if name != "default" {
+			file.Name = name
+			file.Path = strings.TrimSpace(file.Path)
+			if file.Path == "" {
+				return nil, fmt.Errorf("missing path in file stats: %s", file.Name)
+			}
+			if file.MaxDepth < 0 {
+				return nil, fmt.Errorf("invalid max_depth in file stats: %s", file.Name)
+			}
+			if file.Enabled {
+				files = append(files, file)
+			}
+		}
+	}
+
+	// map order is random
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	return files, nil
+}
+
 func unmarshalPrometheusExporters(configPath string) ([]*PrometheusExporter, error) {
 	v := viper.New()
 	v.SetConfigFile(configPath)
@@ -357,6 +862,116 @@ func unmarshalPrometheusExporters(configPath string) ([]*PrometheusExporter, err
 	return exporters, nil
 }
 
+// ValidateProbe sets the defaults of the probe and checks the required options of the probe type
+func ValidateProbe(probe *Probe) error {
+	if probe.Interval <= 0 {
+		probe.Interval = 60
+	}
+	if probe.Timeout <= 0 {
+		probe.Timeout = 10
+	}
+	if probe.Timeout > probe.Interval {
+		return fmt.Errorf("timeout must be lower or equal to interval in probe: %s", probe.Name)
+	}
+
+	regexes := []string{}
+	probe.Type = strings.ToLower(strings.TrimSpace(probe.Type))
+	switch probe.Type {
+	case ProbeTypeHTTP:
+		u, err := url.Parse(strings.TrimSpace(probe.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid url in probe: %s", probe.Name)
+		}
+		probe.URL = u.String()
+		probe.Method = strings.ToUpper(strings.TrimSpace(probe.Method))
+		if probe.Method == "" {
+			probe.Method = "GET"
+		}
+		expectedStatus := make([]string, 0, len(probe.ExpectedStatus))
+		for _, status := range probe.ExpectedStatus {
+			status = strings.TrimSpace(status)
+			if status == "" {
+				continue
+			}
+			if code, err := strconv.Atoi(status); err != nil || code < 100 || code > 599 {
+				return fmt.Errorf("invalid expected_status '%s' in probe: %s", status, probe.Name)
+			}
+			expectedStatus = append(expectedStatus, status)
+		}
+		if len(expectedStatus) == 0 {
+			expectedStatus = []string{"200"}
+		}
+		probe.ExpectedStatus = expectedStatus
+		regexes = append(regexes, probe.BodyRegex)
+	case ProbeTypeTCP:
+		if _, _, err := net.SplitHostPort(strings.TrimSpace(probe.Address)); err != nil {
+			return fmt.Errorf("invalid address in probe %s, expected host:port: %s", probe.Name, err)
+		}
+		probe.Address = strings.TrimSpace(probe.Address)
+		regexes = append(regexes, probe.BannerRegex)
+	case ProbeTypeDNS:
+		probe.Query = strings.TrimSpace(probe.Query)
+		if probe.Query == "" {
+			return fmt.Errorf("missing query in probe: %s", probe.Name)
+		}
+		probe.RecordType = strings.ToUpper(strings.TrimSpace(probe.RecordType))
+		switch probe.RecordType {
+		case "":
+			probe.RecordType = "A"
+		case "A", "AAAA", "CNAME", "MX", "NS", "TXT":
+		default:
+			return fmt.Errorf("unsupported record_type '%s' in probe: %s", probe.RecordType, probe.Name)
+		}
+		probe.Resolver = strings.TrimSpace(probe.Resolver)
+		if probe.Resolver != "" {
+			if _, _, err := net.SplitHostPort(probe.Resolver); err != nil {
+				probe.Resolver = net.JoinHostPort(probe.Resolver, "53")
+			}
+		}
+		regexes = append(regexes, probe.AnswerRegex)
+	default:
+		return fmt.Errorf("unknown type '%s' in probe: %s", probe.Type, probe.Name)
+	}
+
+	for _, regex := range regexes {
+		if _, err := regexp.Compile(regex); err != nil {
+			return fmt.Errorf("invalid regex in probe %s: %s", probe.Name, err)
+		}
+	}
+	return nil
+}
+
+func unmarshalProbes(configPath string) ([]*Probe, error) {
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	v.SetConfigType("ini")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	cfg := map[string]*Probe{}
+
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+
+	probes := make([]*Probe, 0)
+	for name, probe := range cfg {
+		if name != "default" {
+			probe.Name = name
+			if err := ValidateProbe(probe); err != nil {
+				return nil, err
+			}
+			if probe.Enabled {
+				probes = append(probes, probe)
+			}
+		}
+	}
+
+	return probes, nil
+}
+
 func (c *Configuration) SaveConfiguration(config []byte) error {
 	if err := os.WriteFile(c.ConfigurationPath, config, 0600); err != nil {
 		return err
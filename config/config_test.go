@@ -153,6 +153,21 @@ var customChecksAgentVersion1Config string = `[default]
   enabled = true
 `
 
+var customChecksRetryConfig string = `
+[check_flaky]
+  command = /usr/lib/nagios/plugins/check_http -H localhost
+  interval = 300
+  timeout = 10
+  enabled = true
+  max_attempts = 3
+  retry_interval = 30
+
+[check_default]
+  command = /usr/lib/nagios/plugins/check_users -w 3 -c 7
+  interval = 120
+  enabled = true
+`
+
 var customChecksAgentEmptyConfig string = ``
 
 var customChecksAgentVersion1ConfigEmptyCommand string = `
@@ -278,6 +293,14 @@ func TestAgentVersion1EmptyConfig(t *testing.T) {
 		t.Error("Alfresco JmxUser expect to be monitorRole")
 	}
 
+	if c.CustomchecksMaxConcurrent != 0 {
+		t.Error("Custom checks max concurrent expect to be 0")
+	}
+
+	if c.CustomchecksSplay != false {
+		t.Error("Custom checks splay expect to be false")
+	}
+
 	if c.OITC.Push != false {
 		t.Error("Push Mode expect to be false")
 	}
@@ -440,6 +463,29 @@ func TestReadCustomChecksConfigAgentVersion1MissingCommandline(t *testing.T) {
 	}
 }
 
+func TestReadCustomChecksConfigRetry(t *testing.T) {
+	cfgdir := saveTempConfig(customChecksRetryConfig, true)
+	defer os.RemoveAll(cfgdir)
+
+	ccc, err := unmarshalCustomChecks(filepath.Join(cfgdir, "customchecks.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, customcheck := range ccc {
+		switch customcheck.Name {
+		case "check_flaky":
+			if customcheck.MaxAttempts != 3 || customcheck.RetryInterval != 30 {
+				t.Error("Custom check check_flaky expected to have 3 attempts with a retry interval of 30")
+			}
+		case "check_default":
+			if customcheck.MaxAttempts != 1 || customcheck.RetryInterval != 120 {
+				t.Error("Custom check check_default expected to have 1 attempt with a retry interval of 120")
+			}
+		}
+	}
+}
+
 func TestReadCustomChecksConfigEmpty(t *testing.T) {
 	cfgdir := saveTempConfig(customChecksAgentEmptyConfig, true)
 	defer os.RemoveAll(cfgdir)
@@ -504,3 +550,251 @@ func TestReadAgentConfigWithCCAndNewConfig(t *testing.T) {
 		t.Error("reload did not work, unexpected number of custom checks (0): ", len(ccc))
 	}
 }
+
+var agentConfigWithIntegrity string = `[default]
+interval = 30
+
+[integrity]
+enabled = true
+allowed-directories = /usr/lib/nagios/plugins,/opt/checks
+hashes = /etc/openitcockpit-agent/customchecks.sha256
+`
+
+func TestParseIntegrityConfiguration(t *testing.T) {
+	integrity, err := ParseIntegrityConfiguration([]byte(agentConfigWithIntegrity))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !integrity.Enable {
+		t.Error("Integrity expect to be enabled")
+	}
+	if len(integrity.AllowedDirectories) != 2 || integrity.AllowedDirectories[1] != "/opt/checks" {
+		t.Error("Integrity allowed directories expect to be /usr/lib/nagios/plugins and /opt/checks: ", integrity.AllowedDirectories)
+	}
+	if integrity.HashesFile != "/etc/openitcockpit-agent/customchecks.sha256" {
+		t.Error("Integrity hashes file expect to be /etc/openitcockpit-agent/customchecks.sha256")
+	}
+
+	disabled, err := ParseIntegrityConfiguration([]byte(agentVersion1Config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disabled.Enable {
+		t.Error("Integrity expect to be disabled by default")
+	}
+	if integrity.Equal(disabled) {
+		t.Error("Integrity configurations expect to be different")
+	}
+
+	cfgdir := saveTempConfig(agentConfigWithIntegrity, false)
+	defer os.RemoveAll(cfgdir)
+
+	c, err := Load(context.Background(), filepath.Join(cfgdir, "config.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !integrity.Equal(c.Integrity) {
+		t.Error("Integrity configuration of loaded config expect to be equal")
+	}
+}
+
+var probesConfig string = `[web]
+enabled = true
+type = http
+url = https://intranet.example.org/health
+expected_status = 200,204
+body_regex = "status.+ok"
+
+[smtp]
+enabled = true
+type = tcp
+address = mail.example.org:25
+banner_regex = ^220
+
+[resolver]
+enabled = true
+type = dns
+query = example.org
+resolver = 10.0.0.53
+interval = 30
+timeout = 5
+
+[disabled]
+enabled = false
+type = tcp
+address = 127.0.0.1:22
+`
+
+func TestReadProbesConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	probesPath := filepath.Join(tmpDir, "probes.ini")
+	if err := os.WriteFile(probesPath, []byte(probesConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	probes, err := unmarshalProbes(probesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(probes) != 3 {
+		t.Fatal("unexpected number of probes (3): ", len(probes))
+	}
+	byName := map[string]*Probe{}
+	for _, probe := range probes {
+		byName[probe.Name] = probe
+	}
+
+	web := byName["web"]
+	if web == nil || web.Method != "GET" || web.Interval != 60 || web.Timeout != 10 || len(web.ExpectedStatus) != 2 || web.ExpectedStatus[1] != "204" || web.BodyRegex != "status.+ok" {
+		t.Error("unexpected http probe: ", web)
+	}
+	if smtp := byName["smtp"]; smtp == nil || smtp.Address != "mail.example.org:25" || smtp.BannerRegex != "^220" {
+		t.Error("unexpected tcp probe: ", smtp)
+	}
+	if dns := byName["resolver"]; dns == nil || dns.RecordType != "A" || dns.Resolver != "10.0.0.53:53" || dns.Interval != 30 || dns.Timeout != 5 {
+		t.Error("unexpected dns probe: ", dns)
+	}
+}
+
+func TestValidateProbe(t *testing.T) {
+	for _, probe := range []*Probe{
+		{Name: "type", Type: "icmp"},
+		{Name: "url", Type: ProbeTypeHTTP, URL: "ftp://example.org"},
+		{Name: "status", Type: ProbeTypeHTTP, URL: "http://example.org", ExpectedStatus: []string{"ok"}},
+		{Name: "regex", Type: ProbeTypeHTTP, URL: "http://example.org", BodyRegex: "("},
+		{Name: "address", Type: ProbeTypeTCP, Address: "example.org"},
+		{Name: "query", Type: ProbeTypeDNS},
+		{Name: "record", Type: ProbeTypeDNS, Query: "example.org", RecordType: "SRV"},
+		{Name: "timeout", Type: ProbeTypeTCP, Address: "example.org:22", Interval: 5, Timeout: 10},
+	} {
+		if err := ValidateProbe(probe); err == nil {
+			t.Error("expected validation error for probe: ", probe.Name)
+		}
+	}
+}
+
+var logfilesConfig string = `[syslog]
+enabled = true
+file = /var/log/syslog
+critical = "(?i)kernel panic"
+warning = "(?i)error"
+
+[disabled]
+enabled = false
+file = /var/log/app/*.log
+warning = WARN
+`
+
+func TestReadLogfilesConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	logfilesPath := filepath.Join(tmpDir, "logfiles.ini")
+	if err := os.WriteFile(logfilesPath, []byte(logfilesConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	logfiles, err := unmarshalLogfiles(logfilesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logfiles) != 1 {
+		t.Fatal("unexpected number of log files (1): ", len(logfiles))
+	}
+	if logfiles[0].Name != "syslog" || logfiles[0].File != "/var/log/syslog" || logfiles[0].Critical != "(?i)kernel panic" || logfiles[0].Warning != "(?i)error" {
+		t.Error("unexpected log file: ", logfiles[0])
+	}
+
+	if err := os.WriteFile(logfilesPath, []byte("[app]\nenabled = true\nfile = /var/log/app.log\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unmarshalLogfiles(logfilesPath); err == nil {
+		t.Error("expected error for log file without regex")
+	}
+}
+
+var fileStatsConfig string = `[spool]
+enabled = true
+path = /var/spool/postfix/deferred
+recursive = true
+max_depth = 2
+
+[backups]
+enabled = true
+path = /var/backups/*.tar.gz
+
+[disabled]
+enabled = false
+path = /tmp
+`
+
+func TestReadFileStatsConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	filesPath := filepath.Join(tmpDir, "filestats.ini")
+	if err := os.WriteFile(filesPath, []byte(fileStatsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := unmarshalFileStats(filesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatal("unexpected number of file stats (2): ", len(files))
+	}
+	if files[0].Name != "backups" || files[0].Path != "/var/backups/*.tar.gz" || files[0].Recursive || files[0].MaxDepth != 0 {
+		t.Error("unexpected file stats: ", files[0])
+	}
+	if files[1].Name != "spool" || files[1].Path != "/var/spool/postfix/deferred" || !files[1].Recursive || files[1].MaxDepth != 2 {
+		t.Error("unexpected file stats: ", files[1])
+	}
+
+	if err := os.WriteFile(filesPath, []byte("[empty]\nenabled = true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unmarshalFileStats(filesPath); err == nil {
+		t.Error("expected error for file stats without path")
+	}
+}
+
+var processGroupsConfig string = `[webserver]
+enabled = true
+name = ^(nginx|apache2)$
+user = ^www-data$
+
+[disabled]
+enabled = false
+cmdline = java
+`
+
+func TestReadProcessGroupsConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	groupsPath := filepath.Join(tmpDir, "processgroups.ini")
+	if err := os.WriteFile(groupsPath, []byte(processGroupsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := unmarshalProcessGroups(groupsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatal("unexpected number of process groups (1): ", len(groups))
+	}
+	if groups[0].Name != "webserver" || groups[0].Process != "^(nginx|apache2)$" || groups[0].User != "^www-data$" || groups[0].Exe != "" {
+		t.Error("unexpected process group: ", groups[0])
+	}
+
+	if err := os.WriteFile(groupsPath, []byte("[empty]\nenabled = true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unmarshalProcessGroups(groupsPath); err == nil {
+		t.Error("expected error for process group without regex")
+	}
+
+	if err := os.WriteFile(groupsPath, []byte("[invalid]\nenabled = true\nname = (nginx\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unmarshalProcessGroups(groupsPath); err == nil {
+		t.Error("expected error for process group with invalid regex")
+	}
+}
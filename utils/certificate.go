@@ -33,6 +33,37 @@ func CertPoolFromFiles(files ...string) (*x509.CertPool, []byte, error) {
 	return p, pem.Bytes(), nil
 }
 
+// ParseCertificates parses all certificates of PEM encoded data or a single DER encoded certificate
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) > 0 {
+		return certs, nil
+	}
+
+	// No pem block found, try DER
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("no valid pem or der encoded certificate found")
+	}
+	return []*x509.Certificate{cert}, nil
+}
+
 // GeneratePrivateKeyIfNotExists checks for keyFile and if it does not exist generates a rsa 4096 bits key
 func GeneratePrivateKeyIfNotExists(keyFile string) error {
 	if _, err := os.Stat(keyFile); os.IsNotExist(err) {
@@ -22,6 +22,12 @@ type CommandResult struct {
 	Stdout                    string `json:"stdout"`
 	RC                        int    `json:"rc"`
 	ExecutionUnixTimestampSec int64  `json:"execution_unix_timestamp_sec"`
+	// Stale is set if the result is older than expected for the check interval
+	Stale bool `json:"stale,omitempty"`
+	// CurrentAttempt, MaxAttempts and StateType (soft or hard) of custom checks
+	CurrentAttempt int64  `json:"current_attempt,omitempty"`
+	MaxAttempts    int64  `json:"max_attempts,omitempty"`
+	StateType      string `json:"state_type,omitempty"`
 }
 
 // Unified exit codes
@@ -41,6 +47,8 @@ type CommandArgs struct {
 	Shell         string
 	PowershellExe string
 	Stdin         string
+	// Integrity policy to verify the executable before the execution (nil = disabled)
+	Integrity *IntegrityPolicy
 }
 
 var (
@@ -159,6 +167,15 @@ func RunCommand(ctx context.Context, commandArgs CommandArgs) (*CommandResult, e
 		return result, err
 	}
 
+	if commandArgs.Integrity != nil {
+		if err := verifyCommandIntegrity(commandArgs.Integrity, args, commandArgs.Shell); err != nil {
+			result.RC = Unknown
+			result.Stdout = fmt.Sprintf("UNKNOWN - %s", err)
+
+			return result, err
+		}
+	}
+
 	if commandArgs.Stdin != "" {
 		// User passed data to put on stdin so put this data on stdin !
 		stdin = commandArgs.Stdin
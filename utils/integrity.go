@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// IntegrityPolicy restricts which executables can be run by RunCommand
+//
+// An executable must be located below one of the AllowedDirectories.
+// If hashes or a public key are configured, the executable also needs a matching SHA-256 hash
+// or a valid detached signature (<executable>.sig) for the public key.
+type IntegrityPolicy struct {
+	AllowedDirectories []string
+	// Hashes maps the absolute path of an executable to its hex encoded SHA-256 hash
+	Hashes map[string]string
+	// PublicKey to verify detached signatures (ed25519, ecdsa or rsa)
+	PublicKey crypto.PublicKey
+}
+
+// IntegrityError is returned if an executable violates the integrity policy
+type IntegrityError struct {
+	Path   string
+	Reason string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("integrity check failed for '%s': %s", e.Path, e.Reason)
+}
+
+// NewIntegrityPolicy creates a policy for the allowed directories and loads the optional hashes file
+// (format of sha256sum: "<hash>  <path>") and the optional PEM encoded public key
+func NewIntegrityPolicy(allowedDirectories []string, hashesFile, publicKeyFile string) (*IntegrityPolicy, error) {
+	p := &IntegrityPolicy{
+		AllowedDirectories: make([]string, 0, len(allowedDirectories)),
+	}
+
+	for _, dir := range allowedDirectories {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		p.AllowedDirectories = append(p.AllowedDirectories, resolvePath(dir))
+	}
+
+	if hashesFile != "" {
+		hashes, err := readHashesFile(hashesFile)
+		if err != nil {
+			return nil, err
+		}
+		p.Hashes = hashes
+	}
+
+	if publicKeyFile != "" {
+		key, err := readPublicKeyFile(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		p.PublicKey = key
+	}
+
+	return p, nil
+}
+
+// resolvePath returns the absolute path without symlinks if possible
+func resolvePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return filepath.Clean(path)
+}
+
+func readHashesFile(hashesFile string) (map[string]string, error) {
+	f, err := os.Open(hashesFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read integrity hashes file: %s", err)
+	}
+	defer f.Close()
+
+	hashes := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %d in integrity hashes file %s", lineNo, hashesFile)
+		}
+		hash := strings.ToLower(fields[0])
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid sha256 hash in line %d of integrity hashes file %s", lineNo, hashesFile)
+		}
+		// sha256sum marks files read in binary mode with a leading *
+		path := strings.TrimPrefix(strings.TrimSpace(fields[1]), "*")
+		hashes[resolvePath(path)] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read integrity hashes file: %s", err)
+	}
+	return hashes, nil
+}
+
+func readPublicKeyFile(publicKeyFile string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(publicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read integrity public key: %s", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("not a valid pem encoded public key %s", publicKeyFile)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse integrity public key %s: %s", publicKeyFile, err)
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type in %s", publicKeyFile)
+	}
+}
+
+// Verify the executable against the policy and return the resolved path of the executable
+func (p *IntegrityPolicy) Verify(executable string) (string, error) {
+	path := executable
+	if !filepath.IsAbs(path) {
+		lookPath, err := exec.LookPath(path)
+		if err != nil {
+			return "", &IntegrityError{Path: executable, Reason: "executable not found"}
+		}
+		path = lookPath
+	}
+	path = resolvePath(path)
+
+	if !p.isAllowedDirectory(path) {
+		return path, &IntegrityError{Path: path, Reason: "executable is not located in an allowed directory"}
+	}
+
+	if len(p.Hashes) == 0 && p.PublicKey == nil {
+		return path, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return path, &IntegrityError{Path: path, Reason: fmt.Sprintf("could not read executable: %s", err)}
+	}
+	digest := sha256.Sum256(content)
+
+	if expected, ok := p.Hashes[path]; ok {
+		if hex.EncodeToString(digest[:]) == expected {
+			return path, nil
+		}
+		return path, &IntegrityError{Path: path, Reason: "sha256 hash does not match"}
+	}
+
+	if p.PublicKey == nil {
+		return path, &IntegrityError{Path: path, Reason: "no sha256 hash configured for executable"}
+	}
+
+	signature, err := readSignature(path + ".sig")
+	if err != nil {
+		return path, &IntegrityError{Path: path, Reason: fmt.Sprintf("could not read signature: %s", err)}
+	}
+	if !p.verifySignature(content, digest[:], signature) {
+		return path, &IntegrityError{Path: path, Reason: "invalid signature"}
+	}
+	return path, nil
+}
+
+func (p *IntegrityPolicy) isAllowedDirectory(path string) bool {
+	for _, dir := range p.AllowedDirectories {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			continue
+		}
+		if rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func readSignature(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// signatures are small, do not read huge files by accident
+	return io.ReadAll(io.LimitReader(f, 64*1024))
+}
+
+// verifySignature checks a detached signature created by e.g. openssl dgst -sha256 -sign (rsa, ecdsa)
+// or openssl pkeyutl -sign -rawin (ed25519)
+func (p *IntegrityPolicy) verifySignature(content, digest, signature []byte) bool {
+	switch key := p.PublicKey.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, content, signature)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest, signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature) == nil
+	}
+	return false
+}
+
+// verifyCommandIntegrity checks the executable (or the script for the windows shells) of the parsed command
+// The verified argument gets replaced by the resolved path, so exactly the verified file will be executed
+func verifyCommandIntegrity(p *IntegrityPolicy, args []string, shell string) error {
+	index := -1
+	if runtime.GOOS == "windows" {
+		switch shell {
+		case "":
+			index = 0
+		case "powershell":
+			index = len(powershellCommand)
+		case "bat":
+			index = len(cmdCommand)
+		case "vbs":
+			index = len(vbsCommand) + len(vbsArgs)
+		}
+	} else if shell == "" {
+		index = 0
+	}
+
+	if index < 0 {
+		return &IntegrityError{Path: shell, Reason: "commands executed through a shell can not be verified"}
+	}
+	if index >= len(args) {
+		return &IntegrityError{Path: strings.Join(args, " "), Reason: "missing executable"}
+	}
+
+	path, err := p.Verify(args[index])
+	if err != nil {
+		return err
+	}
+	args[index] = path
+	return nil
+}
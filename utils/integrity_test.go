@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeIntegrityTestScript(t *testing.T, dir string) (string, []byte) {
+	script := filepath.Join(dir, "check_test.sh")
+	content := []byte("#!/bin/sh\necho integrity ok\n")
+	if err := os.WriteFile(script, content, 0700); err != nil {
+		t.Fatal(err)
+	}
+	return script, content
+}
+
+func TestIntegrityPolicyAllowedDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+	script, _ := writeIntegrityTestScript(t, tmpDir)
+
+	policy, err := NewIntegrityPolicy([]string{tmpDir}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Verify(script); err != nil {
+		t.Fatal("expected script in allowed directory to pass: ", err)
+	}
+
+	policy, err = NewIntegrityPolicy([]string{filepath.Join(tmpDir, "other")}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var integrityErr *IntegrityError
+	if _, err := policy.Verify(script); !errors.As(err, &integrityErr) {
+		t.Fatal("expected integrity error for script outside of allowed directories: ", err)
+	}
+
+	// a path traversal must not escape the allowed directory
+	if _, err := policy.Verify(filepath.Join(tmpDir, "other", "..", "check_test.sh")); err == nil {
+		t.Fatal("expected integrity error for path traversal")
+	}
+
+	if _, err := (&IntegrityPolicy{}).Verify(script); err == nil {
+		t.Fatal("expected integrity error for empty policy")
+	}
+}
+
+func TestIntegrityPolicyHashes(t *testing.T) {
+	tmpDir := t.TempDir()
+	script, content := writeIntegrityTestScript(t, tmpDir)
+
+	digest := sha256.Sum256(content)
+	hashesFile := filepath.Join(tmpDir, "checks.sha256")
+	hashes := fmt.Sprintf("# generated by sha256sum\n%s  %s\n", hex.EncodeToString(digest[:]), script)
+	if err := os.WriteFile(hashesFile, []byte(hashes), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := NewIntegrityPolicy([]string{tmpDir}, hashesFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Verify(script); err != nil {
+		t.Fatal("expected script with matching hash to pass: ", err)
+	}
+
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nrm -rf /\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Verify(script); err == nil || !strings.Contains(err.Error(), "hash does not match") {
+		t.Fatal("expected hash mismatch: ", err)
+	}
+
+	other := filepath.Join(tmpDir, "check_other.sh")
+	if err := os.WriteFile(other, content, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Verify(other); err == nil {
+		t.Fatal("expected integrity error for script without hash")
+	}
+}
+
+func TestIntegrityPolicySignature(t *testing.T) {
+	tmpDir := t.TempDir()
+	script, content := writeIntegrityTestScript(t, tmpDir)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(tmpDir, "checks.pub")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := NewIntegrityPolicy([]string{tmpDir}, "", keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Verify(script); err == nil {
+		t.Fatal("expected integrity error for missing signature")
+	}
+
+	if err := os.WriteFile(script+".sig", ed25519.Sign(priv, content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Verify(script); err != nil {
+		t.Fatal("expected script with valid signature to pass: ", err)
+	}
+
+	if err := os.WriteFile(script+".sig", ed25519.Sign(priv, []byte("something else")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Verify(script); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Fatal("expected invalid signature: ", err)
+	}
+}
+
+func TestRunCommandIntegrity(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	tmpDir := t.TempDir()
+	script, _ := writeIntegrityTestScript(t, tmpDir)
+
+	policy, err := NewIntegrityPolicy([]string{tmpDir}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := RunCommand(context.Background(), CommandArgs{
+		Command:   script,
+		Timeout:   5 * time.Second,
+		Integrity: policy,
+	})
+	if err != nil || !strings.Contains(result.Stdout, "integrity ok") {
+		t.Fatal("expected allowed script to be executed: ", err, result)
+	}
+
+	result, err = RunCommand(context.Background(), CommandArgs{
+		Command:   "echo not allowed",
+		Timeout:   5 * time.Second,
+		Integrity: policy,
+	})
+	if err == nil || result.RC != Unknown || !strings.Contains(result.Stdout, "integrity check failed") {
+		t.Fatal("expected integrity error result: ", err, result)
+	}
+
+	result, err = RunCommand(context.Background(), CommandArgs{
+		Command:   script,
+		Shell:     "/bin/sh",
+		Timeout:   5 * time.Second,
+		Integrity: policy,
+	})
+	if err == nil || result.RC != Unknown {
+		t.Fatal("expected integrity error for shell command: ", err, result)
+	}
+}
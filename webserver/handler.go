@@ -272,6 +272,22 @@ func (w *handler) handleConfigPush(response http.ResponseWriter, request *http.R
 		return
 	}
 
+	// the integrity settings protect the agent against a configuration push of malicious custom checks
+	// so they can only be changed locally
+	if w.Configuration.Integrity != nil && w.Configuration.Integrity.Enable {
+		integrity, err := config.ParseIntegrityConfiguration(cfgData)
+		if err != nil {
+			log.Errorln("Webserver: Could not parse configuration for configuration push: ", err)
+			http.Error(response, "invalid configuration", http.StatusBadRequest)
+			return
+		}
+		if !integrity.Equal(w.Configuration.Integrity) {
+			log.Errorln("Webserver: configuration push tried to change the custom check integrity settings")
+			http.Error(response, "integrity settings can not be changed by configuration push", http.StatusForbidden)
+			return
+		}
+	}
+
 	if err := w.Configuration.SaveConfiguration(cfgData); err != nil {
 		log.Errorln("Webserver: ", err)
 	}
@@ -230,3 +230,68 @@ func TestWebserverHandlerConfig(t *testing.T) {
 
 	w.Shutdown()
 }
+
+func TestWebserverHandlerConfigIntegrity(t *testing.T) {
+	state := make(chan []byte)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tmpdir, err := os.MkdirTemp(os.TempDir(), "*-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpdir)
+	}()
+	cfgPath := filepath.Join(tmpdir, "config.ini")
+
+	w := &handler{
+		StateInput: state,
+		Configuration: &config.Configuration{
+			ConfigurationPath:    cfgPath,
+			CustomchecksFilePath: filepath.Join(tmpdir, "customchecks.ini"),
+			ConfigUpdate:         true,
+			Integrity: &config.IntegrityConfiguration{
+				Enable:             true,
+				AllowedDirectories: []string{"/opt/checks"},
+			},
+			Prometheus: &config.PrometheusConfiguration{
+				ExportersFilePath: filepath.Join(tmpdir, "prometheus_exporters.ini"),
+			},
+		},
+	}
+
+	ts := httptest.NewServer(w.Handler())
+	defer ts.Close()
+	w.Start(ctx)
+
+	push := func(cfg string) int {
+		data, err := json.Marshal(&configurationPush{
+			Configuration: base64.StdEncoding.EncodeToString([]byte(cfg)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(ts.URL+"/config", "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := push("[default]\n"); status != http.StatusForbidden {
+		t.Error("expected configuration push which disables integrity to be forbidden: ", status)
+	}
+	if _, err := os.Stat(cfgPath); !os.IsNotExist(err) {
+		t.Error("configuration file was written")
+	}
+
+	if status := push("[default]\n[integrity]\nenabled = true\nallowed-directories = /opt/checks\n"); status != http.StatusOK {
+		t.Error("expected configuration push with unchanged integrity settings to succeed: ", status)
+	}
+
+	w.Shutdown()
+}